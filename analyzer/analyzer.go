@@ -9,28 +9,141 @@ import (
 )
 
 type WrapperTypeData struct {
-	Pkg       *types.Package
-	NamedType *types.Named
+	Pkg             *types.Package
+	NamedType       *types.Named
+	ConstructorName string
+	// The type of the wrapped implementation, taken by the constructor.
+	WrappedType types.Type
+	// The type returned by the constructor.
+	// It's the source interface, the extracted interface,
+	// or a pointer to the wrapper for concrete source types, function types and templates with the returnWrapper option.
+	ResultType types.Type
+	// The interface made of the methods of a concrete source type, declared alongside the wrapper. Nil if there is none.
+	ExtractedInterface *types.Named
 }
 
-func GetWrapperTypeData(sourceData *parser.SourceData, templateData *usertemplate.TemplateData) *WrapperTypeData {
-	wrapperPkg := types.NewPackage(templateData.Package, templateData.Package)
+// NameOverrides replace the names which would otherwise come from the template or the generation mode.
+// Empty fields keep the default.
+type NameOverrides struct {
+	// Replaces the whole name of the generated type, so the suffix isn't used.
+	TypeName        string
+	Suffix          string
+	Package         string
+	ConstructorName string
+}
+
+// GetWrapperTypeData returns the type data for a wrapper of the source type.
+// If the source type is concrete, and the extracted interface name isn't empty,
+// the wrapper wraps and implements an interface of that name, made of the methods of the source type.
+func GetWrapperTypeData(sourceData *parser.SourceData, templateData *usertemplate.TemplateData, overrides *NameOverrides, extractedInterface string) *WrapperTypeData {
+	wrapperPkg := getPackage(orDefault(overrides.Package, templateData.Package), sourceData)
 
-	addImports(wrapperPkg, sourceData)
+	var extracted *types.Named
+	wrappedType := sourceData.SourceType
+	if sourceData.Concrete && extractedInterface != "" {
+		extracted = types.NewNamed(types.NewTypeName(0, wrapperPkg, extractedInterface, nil), sourceData.UnderlyingInterface, nil)
+		wrappedType = extracted
+	}
 
-	wrapped := types.NewVar(0, wrapperPkg, "wrapped", sourceData.NamedType)
+	wrapped := types.NewVar(0, wrapperPkg, "wrapped", wrappedType)
 
-	wrapperName := fmt.Sprintf("%s%s", sourceData.NamedType.Obj().Name(), templateData.Suffix)
+	wrapperName := getTypeName(sourceData, templateData.Suffix, overrides)
 
 	newStruct := types.NewStruct([]*types.Var{wrapped}, []string{})
 
-	wrapperTypeName := types.NewTypeName(0, wrapperPkg, wrapperName, newStruct)
-	wrapperNamedType := types.NewNamed(wrapperTypeName, wrapperTypeName.Type(), nil)
+	typeData := newTypeData(wrapperPkg, wrapperName, newStruct, overrides)
+	typeData.WrappedType = wrappedType
+	typeData.ResultType = wrappedType
+	typeData.ExtractedInterface = extracted
+	if (sourceData.Concrete && extracted == nil) || sourceData.FuncType || templateData.ReturnWrapper {
+		typeData.ResultType = types.NewPointer(typeData.NamedType)
+	}
+
+	return typeData
+}
+
+// GetStandaloneTypeData returns the type data for a standalone implementation of the source interface, like a mock,
+// which doesn't wrap anything.
+func GetStandaloneTypeData(sourceData *parser.SourceData, packageName, suffix string, overrides *NameOverrides) *WrapperTypeData {
+	standalonePkg := getPackage(orDefault(overrides.Package, packageName), sourceData)
+
+	standaloneName := getTypeName(sourceData, suffix, overrides)
+
+	return newTypeData(standalonePkg, standaloneName, types.NewStruct(nil, nil), overrides)
+}
+
+// GetAdapterTypeData returns the type data for an adapter, which wraps the source type and implements the target interface.
+// The adapter is named after both types.
+// example: RepoV1ToRepoV2
+func GetAdapterTypeData(sourceData, targetData *parser.SourceData, packageName string, overrides *NameOverrides) *WrapperTypeData {
+	adapterPkg := getPackage(orDefault(overrides.Package, packageName), sourceData, targetData)
+
+	wrapped := types.NewVar(0, adapterPkg, "wrapped", sourceData.SourceType)
+
+	adapterName := getTypeName(sourceData, fmt.Sprintf("To%s", targetData.NamedType.Obj().Name()), overrides)
+
+	typeData := newTypeData(adapterPkg, adapterName, types.NewStruct([]*types.Var{wrapped}, []string{}), overrides)
+	typeData.WrappedType = sourceData.SourceType
+	typeData.ResultType = targetData.SourceType
+
+	return typeData
+}
+
+// GetExtractedInterfaceData returns the type data for the declaration of an interface made of the methods of the source type.
+// The interface is put in the source package, unless the package is overridden.
+func GetExtractedInterfaceData(sourceData *parser.SourceData, name string, overrides *NameOverrides) *WrapperTypeData {
+	pkg := getPackage(orDefault(overrides.Package, sourceData.Package.Name()), sourceData)
+
+	extracted := types.NewNamed(types.NewTypeName(0, pkg, name, nil), sourceData.UnderlyingInterface, nil)
+
+	return &WrapperTypeData{
+		Pkg:                pkg,
+		NamedType:          extracted,
+		ExtractedInterface: extracted,
+	}
+}
+
+// The name of the generated type is the name of the source type followed by the suffix, unless overridden.
+// example: MyInterfaceLogs
+func getTypeName(sourceData *parser.SourceData, suffix string, overrides *NameOverrides) string {
+	if overrides.TypeName != "" {
+		return overrides.TypeName
+	}
+	return fmt.Sprintf("%s%s", sourceData.NamedType.Obj().Name(), orDefault(overrides.Suffix, suffix))
+}
+
+func newTypeData(pkg *types.Package, name string, underlying *types.Struct, overrides *NameOverrides) *WrapperTypeData {
+	typeName := types.NewTypeName(0, pkg, name, underlying)
+	namedType := types.NewNamed(typeName, typeName.Type(), nil)
 
 	return &WrapperTypeData{
-		Pkg:       wrapperPkg,
-		NamedType: wrapperNamedType,
+		Pkg:             pkg,
+		NamedType:       namedType,
+		ConstructorName: orDefault(overrides.ConstructorName, fmt.Sprintf("New%s", name)),
+	}
+}
+
+func orDefault(value, defaultValue string) string {
+	if value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// Returns the package of the generated code, importing the packages of the sources.
+// If the name is the one of a source package, the code is generated into it, so that its types aren't qualified.
+func getPackage(packageName string, sourceData ...*parser.SourceData) *types.Package {
+	for _, source := range sourceData {
+		if source.Package.Name() == packageName {
+			return source.Package
+		}
+	}
+
+	pkg := types.NewPackage(packageName, packageName)
+	for _, source := range sourceData {
+		addImports(pkg, source)
 	}
+	return pkg
 }
 
 func addImports(wrapperPkg *types.Package, sourceData *parser.SourceData) {
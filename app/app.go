@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"path"
+	"strings"
 
 	"github.com/cube2222/StatsGenerator/analyzer"
 	"github.com/cube2222/StatsGenerator/generator"
@@ -22,9 +23,65 @@ type App struct {
 }
 
 type Config struct {
-	InterfaceName  string
-	TemplatePath   string
+	InterfaceName string
+	// Paths or built-in names of the wrapper templates. Multiple templates generate a decorator chain,
+	// with the first template being the outermost layer.
+	TemplatePaths  []string
 	OutputFilePath string
+	Mode           string
+	// Names or regular expressions of the methods to instrument, all methods if empty.
+	IncludeMethods []string
+	// Names or regular expressions of the methods which are delegated directly instead of being instrumented.
+	ExcludeMethods []string
+	// Merge multiple templates into a single wrapper type, instead of generating a decorator chain.
+	Merge bool
+	// Values of the template parameters, by name. They take precedence over the ones from the manifest.
+	TemplateParams map[string]string
+	// Path of a file with values of the template parameters, see usertemplate.ReadParamsManifest. Optional.
+	ParamsManifest string
+	// Name of the interface to extract from a concrete source type, which the wrapper then wraps and implements.
+	ExtractInterface string
+	// The type adapted to the interface in adapt mode.
+	AdaptFrom string
+	// Override the names of the generated code, empty ones keep the names given by the template or mode.
+	NameOverrides *analyzer.NameOverrides
+}
+
+// The available generation modes.
+const (
+	// Wraps the interface using a template.
+	ModeWrapper = "wrapper"
+	// Generates a standalone mock of the interface, with call expectations.
+	ModeMock = "mock"
+	// Generates a standalone fake of the interface, with overridable methods.
+	ModeFake = "fake"
+	// Generates the declaration of an interface made of the methods of a type, named by ExtractInterface.
+	ModeExtract = "extract"
+	// Generates an adapter implementing the interface using the AdaptFrom type.
+	ModeAdapt = "adapt"
+	// Wraps the interface using the interceptor template, so that every call goes through a runtime.Interceptor.
+	ModeInterceptor = "interceptor"
+)
+
+// The modes supporting function types, which are wrapped as if they were an interface with a single method.
+var funcTypeModes = map[string]bool{
+	ModeWrapper:     true,
+	ModeInterceptor: true,
+}
+
+// The built-in template used in interceptor mode.
+const interceptorTemplate = "interceptor"
+
+// The packages generated mocks and fakes are put in.
+const (
+	mockPackage    = "mocks"
+	fakePackage    = "fakes"
+	adapterPackage = "adapters"
+)
+
+type codeGenerator interface {
+	Generate() error
+	GetBytes() []byte
 }
 
 func NewApp(config *Config) (*App, error) {
@@ -52,28 +109,193 @@ func NewApp(config *Config) (*App, error) {
 func (a *App) Run() error {
 	defer a.output.Close()
 
+	if a.config.InterfaceName == "" {
+		log.Fatal("The interface to generate code for is required")
+	}
+
+	if a.config.Mode == ModeAdapt {
+		return a.runAdapter()
+	}
+
 	sourceData, err := parser.ParseDirectory(".", a.config.InterfaceName)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if sourceData.FuncType && !funcTypeModes[a.config.Mode] {
+		log.Fatalf("%v is a function type, which isn't supported in %s mode", a.config.InterfaceName, a.config.Mode)
+	}
+
+	var g codeGenerator
+	switch a.config.Mode {
+	case ModeMock:
+		mockTypeData := analyzer.GetStandaloneTypeData(sourceData, mockPackage, "Mock", a.config.NameOverrides)
+
+		g = generator.NewMockGenerator(sourceData, mockTypeData)
+	case ModeFake:
+		fakeTypeData := analyzer.GetStandaloneTypeData(sourceData, fakePackage, "Fake", a.config.NameOverrides)
+
+		g = generator.NewFakeGenerator(sourceData, fakeTypeData)
+	case ModeExtract:
+		filter, err := generator.NewMethodFilter(a.config.IncludeMethods, a.config.ExcludeMethods)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		interfaceData := analyzer.GetExtractedInterfaceData(sourceData, a.config.ExtractInterface, a.config.NameOverrides)
 
-	tmplConfig := &usertemplate.WrapperTemplateConfig{
-		Path: a.config.TemplatePath,
+		g = generator.NewInterfaceGenerator(sourceData, interfaceData, filter)
+	default:
+		g, err = a.getWrapperGenerator(sourceData)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
-	templateData, err := usertemplate.GetWrapperTemplate(tmplConfig)
+
+	a.generate(g)
+
+	return nil
+}
+
+func (a *App) runAdapter() error {
+	// The types are parsed together, so that the types of their methods can be compared.
+	sourceData, err := parser.ParseDirectoryTypes(".", a.config.AdaptFrom, a.config.InterfaceName)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if sourceData[1].Concrete || sourceData[1].FuncType {
+		log.Fatalf("%v has to be an interface to adapt to", a.config.InterfaceName)
+	}
+
+	adapterTypeData := analyzer.GetAdapterTypeData(sourceData[0], sourceData[1], adapterPackage, a.config.NameOverrides)
+
+	a.generate(generator.NewAdapterGenerator(sourceData[0], sourceData[1], adapterTypeData))
 
-	wrapperTypeData := analyzer.GetWrapperTypeData(sourceData, templateData)
+	return nil
+}
 
-	g := generator.NewWrapperGenerator(sourceData, wrapperTypeData, templateData)
-	g.Generate()
+func (a *App) generate(g codeGenerator) {
+	err := g.Generate()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	err = printer.Print(a.output, g.GetBytes(), nil)
 	if err != nil {
 		log.Fatal(err)
 	}
+}
 
-	return nil
+// Returns the generator of a wrapper for a single template, and of a merged wrapper or a decorator chain for multiple ones.
+func (a *App) getWrapperGenerator(sourceData *parser.SourceData) (codeGenerator, error) {
+	templatePaths := a.config.TemplatePaths
+	if a.config.Mode == ModeInterceptor {
+		if len(templatePaths) > 0 {
+			return nil, errors.Errorf("Templates can't be used in %s mode", ModeInterceptor)
+		}
+		templatePaths = []string{interceptorTemplate}
+	}
+	if len(templatePaths) == 0 {
+		return nil, errors.Errorf("A template is required in %s mode", ModeWrapper)
+	}
+	chain := len(templatePaths) > 1 && !a.config.Merge
+	if chain && (a.config.NameOverrides.TypeName != "" || a.config.NameOverrides.Suffix != "" || a.config.NameOverrides.ConstructorName != "") {
+		return nil, errors.New("The type name, suffix and constructor name can't be overridden when generating a decorator chain")
+	}
+
+	if a.config.ExtractInterface != "" && !sourceData.Concrete {
+		return nil, errors.Errorf("%v isn't a concrete type, interfaces can only be extracted from concrete types", a.config.InterfaceName)
+	}
+	if chain && sourceData.Concrete && a.config.ExtractInterface == "" {
+		return nil, errors.New("Decorator chains of concrete types require an extracted interface for the layers to wrap")
+	}
+
+	filter, err := generator.NewMethodFilter(a.config.IncludeMethods, a.config.ExcludeMethods)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := a.getTemplateParams()
+	if err != nil {
+		return nil, err
+	}
+
+	templates := []*usertemplate.TemplateData{}
+	declaredParams := map[string]bool{}
+	for _, templatePath := range templatePaths {
+		tmplConfig := &usertemplate.WrapperTemplateConfig{
+			Path:                templatePath,
+			Params:              params,
+			IgnoreUnknownParams: len(templatePaths) > 1,
+		}
+		templateData, err := usertemplate.GetWrapperTemplate(tmplConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Couldn't load template %v", templatePath)
+		}
+		for _, param := range templateData.ParamDeclarations {
+			declaredParams[param.Name] = true
+		}
+		templates = append(templates, templateData)
+	}
+	for name := range params {
+		if !declaredParams[name] {
+			return nil, errors.Errorf("Unknown template parameter %v", name)
+		}
+	}
+	// The code is generated into the package of the first template.
+	if templates[0].Package == "" && a.config.NameOverrides.Package == "" {
+		return nil, errors.Errorf("Template %v has no Package section, and no package is given using --package", templatePaths[0])
+	}
+
+	if len(templates) == 1 {
+		wrapperTypeData := analyzer.GetWrapperTypeData(sourceData, templates[0], a.config.NameOverrides, a.config.ExtractInterface)
+
+		return generator.NewWrapperGenerator(sourceData, wrapperTypeData, templates[0], filter), nil
+	}
+
+	// Everything goes into the package of the first template.
+	overrides := *a.config.NameOverrides
+	if overrides.Package == "" {
+		overrides.Package = templates[0].Package
+	}
+
+	if a.config.Merge {
+		// The merged type is named using the suffixes of all the templates.
+		// example: MyInterfaceLogsRetry
+		suffixes := []string{}
+		returnWrapper := false
+		for _, templateData := range templates {
+			suffixes = append(suffixes, templateData.Suffix)
+			returnWrapper = returnWrapper || templateData.ReturnWrapper
+		}
+		naming := &usertemplate.TemplateData{Suffix: strings.Join(suffixes, ""), ReturnWrapper: returnWrapper}
+		wrapperTypeData := analyzer.GetWrapperTypeData(sourceData, naming, &overrides, a.config.ExtractInterface)
+
+		return generator.NewMergedWrapperGenerator(sourceData, wrapperTypeData, templates, filter), nil
+	}
+
+	layers := []*generator.WrapperGenerator{}
+	for _, templateData := range templates {
+		wrapperTypeData := analyzer.GetWrapperTypeData(sourceData, templateData, &overrides, a.config.ExtractInterface)
+
+		layers = append(layers, generator.NewWrapperGenerator(sourceData, wrapperTypeData, templateData, filter))
+	}
+
+	return generator.NewChainGenerator(sourceData, layers), nil
+}
+
+// Returns the values of the template parameters from the manifest, overridden by the ones given directly.
+func (a *App) getTemplateParams() (map[string]string, error) {
+	if a.config.ParamsManifest == "" {
+		return a.config.TemplateParams, nil
+	}
+
+	params, err := usertemplate.ReadParamsManifest(a.config.ParamsManifest)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range a.config.TemplateParams {
+		params[name] = value
+	}
+
+	return params, nil
 }
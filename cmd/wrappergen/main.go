@@ -3,24 +3,70 @@ package main
 import (
 	"log"
 
+	"github.com/cube2222/StatsGenerator/analyzer"
 	"github.com/cube2222/StatsGenerator/app"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 var (
-	InterfaceName  = kingpin.Flag("interface", "Interface to wrap.").Short('i').Required().String()
-	TemplatePath   = kingpin.Flag("template", "Path of wrapper template to use.").Short('t').Required().String()
-	OutputFilePath = kingpin.Flag("output", "Optional output file.").Short('o').String()
+	InterfaceName    = kingpin.Flag("interface", "Interface or concrete type to wrap. Prefix a concrete type with * to wrap pointers to it, and qualify it with the name of an imported package to use a type from that package. Required by the wrap command.").Short('i').String()
+	TemplatePaths    = kingpin.Flag("template", "Path of wrapper template to use, or the name of a built-in template. Required in wrapper mode. Repeatable, multiple templates generate a decorator chain, the first one outermost.").Short('t').Strings()
+	Merge            = kingpin.Flag("merge", "Merge multiple templates into a single wrapper type, nesting their method bodies using Next, instead of generating a decorator chain.").Bool()
+	OutputFilePath   = kingpin.Flag("output", "Optional output file.").Short('o').String()
+	IncludeMethods   = kingpin.Flag("include-methods", "Name or regular expression of a method to instrument, or to extract, all methods are used if none are given. Repeatable.").Strings()
+	ExcludeMethods   = kingpin.Flag("exclude-methods", "Name or regular expression of a method to pass straight through to the wrapped implementation. Repeatable.").Strings()
+	TemplateParams   = kingpin.Flag("set", "Value of a template parameter, as name=value. Repeatable.").StringMap()
+	ParamsManifest   = kingpin.Flag("manifest", "File with values of template parameters, one name=value per line. Values given with --set take precedence.").String()
+	TypeName         = kingpin.Flag("type-name", "Name of the generated type, overrides the interface name followed by the suffix.").String()
+	Suffix           = kingpin.Flag("suffix", "Suffix of the generated type name, overrides the one from the template.").String()
+	Package          = kingpin.Flag("package", "Package of the generated code, overrides the one from the template.").String()
+	ConstructorName  = kingpin.Flag("constructor-name", "Name of the generated constructor, New followed by the type name by default.").String()
+	ExtractInterface = kingpin.Flag("extract-interface", "Name of an interface to declare alongside the wrapper of a concrete type, made of its exported methods. The wrapper then wraps and implements it.").String()
+	Mode             = kingpin.Flag("mode", "What to generate: a wrapper using the template, a mock, a fake, or a wrapper calling an interceptor for every call.").Short('m').Default(app.ModeWrapper).Enum(app.ModeWrapper, app.ModeMock, app.ModeFake, app.ModeInterceptor)
+
+	WrapCommand = kingpin.Command("wrap", "Generate a wrapper, a mock or a fake of the interface.").Default()
+
+	ExtractCommand = kingpin.Command("extract", "Generate an interface declaration with the exported methods of a type.")
+	ExtractType    = ExtractCommand.Flag("type", "Type to extract the interface from, given like the interface to wrap.").Required().String()
+	ExtractName    = ExtractCommand.Flag("name", "Name of the extracted interface.").Required().String()
+
+	AdaptCommand = kingpin.Command("adapt", "Generate an adapter implementing an interface by delegating to the identically named methods of another type.")
+	AdaptFrom    = AdaptCommand.Flag("from", "Type to adapt, given like the interface to wrap.").Required().String()
+	AdaptTo      = AdaptCommand.Flag("to", "Interface implemented by the adapter.").Required().String()
 )
 
 func main() {
 	kingpin.Version("0.0.1")
-	kingpin.Parse()
+	command := kingpin.Parse()
 
 	conf := &app.Config{
-		InterfaceName:  *InterfaceName,
-		TemplatePath:   *TemplatePath,
-		OutputFilePath: *OutputFilePath,
+		InterfaceName:    *InterfaceName,
+		TemplatePaths:    *TemplatePaths,
+		OutputFilePath:   *OutputFilePath,
+		Merge:            *Merge,
+		Mode:             *Mode,
+		IncludeMethods:   *IncludeMethods,
+		ExcludeMethods:   *ExcludeMethods,
+		TemplateParams:   *TemplateParams,
+		ParamsManifest:   *ParamsManifest,
+		ExtractInterface: *ExtractInterface,
+		NameOverrides: &analyzer.NameOverrides{
+			TypeName:        *TypeName,
+			Suffix:          *Suffix,
+			Package:         *Package,
+			ConstructorName: *ConstructorName,
+		},
+	}
+
+	switch command {
+	case ExtractCommand.FullCommand():
+		conf.Mode = app.ModeExtract
+		conf.InterfaceName = *ExtractType
+		conf.ExtractInterface = *ExtractName
+	case AdaptCommand.FullCommand():
+		conf.Mode = app.ModeAdapt
+		conf.InterfaceName = *AdaptTo
+		conf.AdaptFrom = *AdaptFrom
 	}
 
 	application, err := app.NewApp(conf)
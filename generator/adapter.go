@@ -0,0 +1,173 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"io"
+	"strings"
+
+	"github.com/cube2222/StatsGenerator/analyzer"
+	"github.com/cube2222/StatsGenerator/parser"
+	"github.com/pkg/errors"
+)
+
+// NewAdapterGenerator creates a generator of an adapter implementing the target interface using the source type.
+// Both have to come from the same parse, so that their types can be compared.
+func NewAdapterGenerator(sourceData, targetData *parser.SourceData, adapterData *analyzer.WrapperTypeData) *AdapterGenerator {
+	return &AdapterGenerator{
+		sourceData:  sourceData,
+		targetData:  targetData,
+		adapterData: adapterData,
+		out:         bytes.NewBuffer(nil),
+	}
+}
+
+// AdapterGenerator generates an adapter, whose methods delegate to the identically named methods of the source type.
+// Arguments and results are converted when their types differ, but have identical underlying types,
+// like a named type and its unnamed form.
+// Methods which can't be delegated panic, and are marked with a TODO comment to be implemented by hand.
+type AdapterGenerator struct {
+	sourceData  *parser.SourceData
+	targetData  *parser.SourceData
+	adapterData *analyzer.WrapperTypeData
+	out         *bytes.Buffer
+}
+
+func (g *AdapterGenerator) Read(p []byte) (n int, err error) {
+	return g.out.Read(p)
+}
+
+func (g *AdapterGenerator) GetBytes() []byte {
+	return g.out.Bytes()
+}
+
+func (g *AdapterGenerator) Generate() error {
+	writePackage(g.out, g.adapterData.Pkg)
+	writeImports(g.out, append(g.sourceData.Package.Imports(), g.targetData.Package.Imports()...))
+
+	writeStructure(g.out, g.adapterData, nil)
+
+	td := getTypeData(g.targetData.NamedType, g.adapterData)
+
+	err := writeConstructor(g.out, td, g.adapterData.WrappedType, g.adapterData.ResultType, g.adapterData.Pkg, g.adapterData.NamedType, nil)
+	if err != nil {
+		return err
+	}
+
+	sourceMethods := map[string]*types.Func{}
+	for i := 0; i < g.sourceData.UnderlyingInterface.NumMethods(); i++ {
+		method := g.sourceData.UnderlyingInterface.Method(i)
+		sourceMethods[method.Name()] = method
+	}
+
+	for i := 0; i < g.targetData.UnderlyingInterface.NumMethods(); i++ {
+		curMethod := g.targetData.UnderlyingInterface.Method(i)
+
+		md := getMethodData(
+			g.targetData.NamedType,
+			curMethod,
+			g.adapterData.Pkg,
+			g.adapterData.NamedType,
+			g.targetData.MethodDocs[curMethod.Name()],
+		)
+		curSignature := curMethod.Type().(*types.Signature)
+
+		WriteSignature(g.out, md, curSignature, g.adapterData.Pkg, g.adapterData.NamedType)
+
+		sourceMethod, ok := sourceMethods[curMethod.Name()]
+		if !ok {
+			writeAdapterStub(g.out, td, md, fmt.Sprintf("%s has no method %s", g.sourceData.NamedType.Obj().Name(), md.FunctionName))
+			continue
+		}
+		err := g.writeAdapterMethodBody(md, curSignature, sourceMethod.Type().(*types.Signature))
+		if err != nil {
+			writeAdapterStub(g.out, td, md, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Writes the body delegating to the source method, converting the arguments and results.
+// Returns an error, and writes nothing, if the signatures aren't compatible.
+func (g *AdapterGenerator) writeAdapterMethodBody(md *MethodData, target, source *types.Signature) error {
+	curPkg := g.adapterData.Pkg
+	sourceName := fmt.Sprintf("%s.%s", g.sourceData.NamedType.Obj().Name(), md.FunctionName)
+
+	if target.Params().Len() != source.Params().Len() || target.Variadic() != source.Variadic() {
+		return errors.Errorf("the arguments of %s don't match", sourceName)
+	}
+	arguments := []string{}
+	for i := 0; i < target.Params().Len(); i++ {
+		argument, ok := convertValue(md.Arguments[i], target.Params().At(i).Type(), source.Params().At(i).Type(), curPkg)
+		if !ok {
+			return errors.Errorf("argument %d of %s has an incompatible type", i, sourceName)
+		}
+		if target.Variadic() && i == target.Params().Len()-1 {
+			if argument != md.Arguments[i] {
+				return errors.Errorf("the variadic argument of %s has a different type", sourceName)
+			}
+			argument += "..."
+		}
+		arguments = append(arguments, argument)
+	}
+
+	if target.Results().Len() != source.Results().Len() {
+		return errors.Errorf("the results of %s don't match", sourceName)
+	}
+	results := []string{}
+	for i := 0; i < target.Results().Len(); i++ {
+		result, ok := convertValue(md.ReturnVars[i], source.Results().At(i).Type(), target.Results().At(i).Type(), curPkg)
+		if !ok {
+			return errors.Errorf("result %d of %s has an incompatible type", i, sourceName)
+		}
+		results = append(results, result)
+	}
+
+	call := fmt.Sprintf("%s.wrapped.%s(%s)", md.ReceiverVar, md.FunctionName, strings.Join(arguments, ", "))
+	if g.sourceData.FuncType {
+		call = fmt.Sprintf("%s.wrapped(%s)", md.ReceiverVar, strings.Join(arguments, ", "))
+	}
+
+	switch {
+	case len(results) == 0:
+		fmt.Fprintf(g.out, " {\n%s\n}\n", call)
+	case strings.Join(results, ", ") == md.ReturnVarsConnected:
+		fmt.Fprintf(g.out, " {\nreturn %s\n}\n", call)
+	default:
+		fmt.Fprintf(g.out, " {\n%s := %s\nreturn %s\n}\n", md.ReturnVarsConnected, call, strings.Join(results, ", "))
+	}
+
+	return nil
+}
+
+// Writes the body of a method which can't be delegated.
+func writeAdapterStub(w io.Writer, td *TypeData, md *MethodData, reason string) {
+	fmt.Fprintf(
+		w,
+		" {\n// TODO: Implement, %s.\npanic(%q)\n}\n",
+		reason,
+		fmt.Sprintf("%s.%s isn't implemented: %s", td.TypeName, md.FunctionName, reason),
+	)
+}
+
+// Returns the expression converting the value to the type, and false if it can't be converted safely.
+// Assignable values are used as they are. Others are only converted if the underlying types are identical,
+// as other conversions, like int to string or float64 to int, change the value.
+// example: pkg.UserID(input0)
+func convertValue(value string, from, to types.Type, curPkg *types.Package) (string, bool) {
+	if types.AssignableTo(from, to) {
+		return value, true
+	}
+	if !types.Identical(from.Underlying(), to.Underlying()) {
+		return "", false
+	}
+	typeString := types.TypeString(to, qualifier(curPkg))
+	switch to.(type) {
+	case *types.Pointer, *types.Signature, *types.Chan:
+		// Otherwise the conversion would be parsed differently, like a dereference of the call.
+		return fmt.Sprintf("(%s)(%s)", typeString, value), true
+	}
+	return fmt.Sprintf("%s(%s)", typeString, value), true
+}
@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"github.com/cube2222/StatsGenerator/usertemplate"
+	"github.com/pkg/errors"
+)
+
+// The prefix of comment lines which are annotations.
+// example: //wrappergen:noretry
+const annotationPrefix = "//wrappergen:"
+
+// Annotations which are handled by the generator itself, any other annotation is only available to templates.
+const (
+	// The method isn't passed to the template, the wrapper delegates it directly to the wrapped implementation.
+	// example: //wrappergen:skip
+	skipAnnotation = "skip"
+	// Adds a key=value pair to the labels of the method, see Annotations.Labels.
+	// example: //wrappergen:label team=billing
+	labelAnnotation = "label"
+	// The results of the method may be cached by its arguments.
+	// They're validated to be strictly comparable if a template uses them as cache keys, see usertemplate.TemplateData.
+	// example: //wrappergen:cacheable
+	cacheableAnnotation = "cacheable"
+)
+
+// Annotations holds the annotations found in the doc comment of a method, by name.
+// Each annotation occurrence has an entry with the rest of its line, which is empty if there is nothing after the name.
+// example: //wrappergen:idempotent results in map[string][]string{"idempotent": {""}}
+type Annotations map[string][]string
+
+// Has reports whether the annotation is present.
+// example: {{if .Annotations.Has "noretry"}}
+func (a Annotations) Has(name string) bool {
+	_, ok := a[name]
+	return ok
+}
+
+// Get returns the value of the first occurrence of the annotation, or an empty string if it's not present.
+// example: {{.Annotations.Get "timeout"}}
+func (a Annotations) Get(name string) string {
+	if len(a[name]) == 0 {
+		return ""
+	}
+	return a[name][0]
+}
+
+// Labels returns the key=value pairs of all label annotations.
+// example: {{range $key, $value := .Annotations.Labels}}{{$key}}: {{$value}}{{end}}
+func (a Annotations) Labels() map[string]string {
+	labels := map[string]string{}
+	for _, label := range a[labelAnnotation] {
+		parts := strings.SplitN(label, "=", 2)
+		if len(parts) < 2 {
+			labels[strings.TrimSpace(parts[0])] = ""
+			continue
+		}
+		labels[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return labels
+}
+
+func getAnnotations(doc *ast.CommentGroup) Annotations {
+	annotations := Annotations{}
+	if doc == nil {
+		return annotations
+	}
+	for _, comment := range doc.List {
+		if !strings.HasPrefix(comment.Text, annotationPrefix) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(comment.Text, annotationPrefix), " ", 2)
+		name := strings.TrimSpace(parts[0])
+		value := ""
+		if len(parts) == 2 {
+			value = strings.TrimSpace(parts[1])
+		}
+		annotations[name] = append(annotations[name], value)
+	}
+	return annotations
+}
+
+// Checks that the method can be handled by the templates as its annotations request.
+func validateAnnotations(md *MethodData, signature *types.Signature, templates []*usertemplate.TemplateData) error {
+	if md.Annotations.Has(cacheableAnnotation) && usesCacheKeys(templates) {
+		cachedResults := 0
+		for i := 0; i < signature.Results().Len(); i++ {
+			if signature.Results().At(i).Type().String() != "error" {
+				cachedResults++
+			}
+		}
+		if cachedResults == 0 {
+			return errors.Errorf("method %s is annotated as %s, but has no results besides the error", md.FunctionName, cacheableAnnotation)
+		}
+		for i := 0; i < signature.Params().Len(); i++ {
+			param := signature.Params().At(i).Type()
+			// Contexts aren't a part of the cache key.
+			if types.TypeString(param, nil) == "context.Context" {
+				continue
+			}
+			if !strictlyComparable(param) {
+				return errors.Errorf(
+					"method %s is annotated as %s, but its argument %s of type %s isn't comparable, or contains interfaces",
+					md.FunctionName, cacheableAnnotation, md.Arguments[i], md.TypedArguments[i].Type,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+func usesCacheKeys(templates []*usertemplate.TemplateData) bool {
+	for _, templateData := range templates {
+		if templateData.CacheKeys {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether values of the type can always be compared, which excludes types containing interfaces,
+// as comparing interfaces holding values like slices panics.
+func strictlyComparable(t types.Type) bool {
+	switch underlying := t.Underlying().(type) {
+	case *types.Interface:
+		return false
+	case *types.Struct:
+		for i := 0; i < underlying.NumFields(); i++ {
+			if !strictlyComparable(underlying.Field(i).Type()) {
+				return false
+			}
+		}
+		return true
+	case *types.Array:
+		return strictlyComparable(underlying.Elem())
+	}
+	return types.Comparable(t)
+}
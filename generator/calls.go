@@ -0,0 +1,150 @@
+package generator
+
+import (
+	"fmt"
+	"go/types"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Writes the structure holding the arguments of a call to the method, and accessors for the recorded calls.
+// Used by generated types which record calls, they have to contain a mu sync.Mutex field and a <method>Calls field.
+func writeCallType(w io.Writer, td *TypeData, md *MethodData, signature *types.Signature, curPkg *types.Package) {
+	tmpl := `
+// %s holds the arguments of a single call to %s.
+type %s struct {
+	%s
+}
+
+// %s returns the recorded calls to %s.
+func (%s *%s) %s() []%s {
+	%s.mu.Lock()
+	defer %s.mu.Unlock()
+	return append([]%s{}, %s.%s...)
+}
+
+// %s returns the number of recorded calls to %s.
+func (%s *%s) %s() int {
+	%s.mu.Lock()
+	defer %s.mu.Unlock()
+	return len(%s.%s)
+}
+`
+	callTypeName := callTypeName(td, md)
+	callsField := fmt.Sprintf("%sCalls", lowercaseFirstLetter(md.FunctionName))
+	callsGetter := fmt.Sprintf("%sCalls", md.FunctionName)
+	callCountGetter := fmt.Sprintf("%sCallCount", md.FunctionName)
+
+	fmt.Fprintf(
+		w,
+		tmpl,
+		callTypeName,
+		md.FunctionName,
+		callTypeName,
+		strings.Join(getCallFields(md, signature, curPkg), "\n"),
+		callsGetter,
+		md.FunctionName,
+		td.ReceiverVar,
+		td.TypeName,
+		callsGetter,
+		callTypeName,
+		td.ReceiverVar,
+		td.ReceiverVar,
+		callTypeName,
+		td.ReceiverVar,
+		callsField,
+		callCountGetter,
+		md.FunctionName,
+		td.ReceiverVar,
+		td.TypeName,
+		callCountGetter,
+		td.ReceiverVar,
+		td.ReceiverVar,
+		td.ReceiverVar,
+		callsField,
+	)
+}
+
+// Calls the function field stored in fn and returns its results.
+func getFunctionFieldCall(md *MethodData, signature *types.Signature) string {
+	fnCall := fmt.Sprintf("fn(%s)", getCallArguments(md, signature))
+	if signature.Results().Len() > 0 {
+		return fmt.Sprintf("return %s", fnCall)
+	}
+	return fmt.Sprintf("%s\nreturn", fnCall)
+}
+
+// The names of the fields and methods generated for the method, by types which record calls.
+func callMemberNames(md *MethodData) []string {
+	return []string{
+		fmt.Sprintf("%sFunc", md.FunctionName),
+		fmt.Sprintf("%sCalls", md.FunctionName),
+		fmt.Sprintf("%sCallCount", md.FunctionName),
+		fmt.Sprintf("%sCalls", lowercaseFirstLetter(md.FunctionName)),
+	}
+}
+
+// Returns an error if a generated field or method has the name of one of the methods of the source interface,
+// as the generated type wouldn't compile.
+// The member names are given for each method, and fixedNames for the type as a whole.
+func checkMemberCollisions(td *TypeData, mds []*MethodData, memberNames func(md *MethodData) []string, fixedNames ...string) error {
+	methods := map[string]bool{}
+	for _, md := range mds {
+		methods[md.FunctionName] = true
+	}
+
+	for _, name := range fixedNames {
+		if methods[name] {
+			return errors.Errorf("the generated %s member %s has the name of a method of %s", td.TypeName, name, td.FullOriginalTypeName)
+		}
+	}
+	for _, md := range mds {
+		for _, name := range memberNames(md) {
+			if methods[name] {
+				return errors.Errorf(
+					"the %s member %s generated for %s has the name of a method of %s",
+					td.TypeName, name, md.FunctionName, td.FullOriginalTypeName,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+func callTypeName(td *TypeData, md *MethodData) string {
+	return fmt.Sprintf("%s%sCall", td.TypeName, md.FunctionName)
+}
+
+// The call structure has an exported field for each argument, the argument name with the first letter capitalized.
+// example: Input0 context.Context
+func getCallFields(md *MethodData, signature *types.Signature, curPkg *types.Package) []string {
+	fields := []string{}
+	for i := 0; i < signature.Params().Len(); i++ {
+		fields = append(fields, fmt.Sprintf(
+			"%s %s",
+			strings.ToUpper(md.Arguments[i][0:1])+md.Arguments[i][1:],
+			types.TypeString(signature.Params().At(i).Type(), qualifier(curPkg)),
+		))
+	}
+	return fields
+}
+
+// Each argument name followed by its type.
+// example: []string{input0 context.Context, input1 int}
+func getParameters(md *MethodData, signature *types.Signature, curPkg *types.Package) []string {
+	parameters := []string{}
+	for i := 0; i < signature.Params().Len(); i++ {
+		parameters = append(parameters, fmt.Sprintf(
+			"%s %s",
+			md.Arguments[i],
+			types.TypeString(signature.Params().At(i).Type(), qualifier(curPkg)),
+		))
+	}
+	return parameters
+}
+
+func lowercaseFirstLetter(s string) string {
+	return strings.ToLower(s[0:1]) + s[1:]
+}
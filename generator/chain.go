@@ -0,0 +1,154 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"io"
+	"strings"
+
+	"github.com/cube2222/StatsGenerator/parser"
+	"github.com/pkg/errors"
+)
+
+// NewChainGenerator creates a generator of a decorator chain out of the given layers, which all have to share a package.
+// The first layer is the outermost one.
+func NewChainGenerator(sourceData *parser.SourceData, layers []*WrapperGenerator) *ChainGenerator {
+	return &ChainGenerator{
+		sourceData: sourceData,
+		layers:     layers,
+		out:        bytes.NewBuffer(nil),
+	}
+}
+
+// ChainGenerator generates multiple wrappers of the source interface in a single file,
+// together with a function composing all of them around an implementation.
+type ChainGenerator struct {
+	sourceData *parser.SourceData
+	layers     []*WrapperGenerator
+	out        *bytes.Buffer
+}
+
+func (g *ChainGenerator) Read(p []byte) (n int, err error) {
+	return g.out.Read(p)
+}
+
+func (g *ChainGenerator) GetBytes() []byte {
+	return g.out.Bytes()
+}
+
+func (g *ChainGenerator) Generate() error {
+	if len(g.layers) == 0 {
+		return errors.New("no layers to generate")
+	}
+	pkg := g.layers[0].wrapperData.Pkg
+
+	writePackage(g.out, pkg)
+	writeImports(g.out, g.sourceData.Package.Imports())
+
+	imports := []string{}
+	seenImports := map[string]bool{}
+	typeNames := map[string]bool{}
+	for _, layer := range g.layers {
+		if layer.wrapperData.Pkg.Name() != pkg.Name() {
+			return errors.Errorf("all layers have to be in the same package, got %s and %s", pkg.Name(), layer.wrapperData.Pkg.Name())
+		}
+		typeName := layer.wrapperData.NamedType.Obj().Name()
+		if typeNames[typeName] {
+			return errors.Errorf("multiple layers generate the type %s, they need different suffixes", typeName)
+		}
+		typeNames[typeName] = true
+
+		for _, i := range layer.imports() {
+			if !seenImports[i] {
+				seenImports[i] = true
+				imports = append(imports, i)
+			}
+		}
+	}
+	writeUserSuppliedImports(g.out, imports)
+	writeExtractedInterface(g.out, g.sourceData, g.layers[0].wrapperData, nil)
+
+	for _, layer := range g.layers {
+		err := layer.writeWrapper(g.out)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't generate the %s layer", layer.wrapperData.NamedType.Obj().Name())
+		}
+	}
+
+	return writeDecorator(g.out, g.sourceData, g.layers)
+}
+
+// Writes the function wrapping an implementation in all the layers, taking the union of their constructor arguments.
+// example: func DecorateMyInterface(wrapped pkg.MyInterface, logger *log.Logger, maxAttempts int) pkg.MyInterface
+func writeDecorator(w io.Writer, sourceData *parser.SourceData, layers []*WrapperGenerator) error {
+	tmpl := `
+// %s wraps the implementation in the %s layers, the first one being the outermost.
+func %s(%s) %s {
+	%s
+	return wrapped
+}
+`
+	curPkg := layers[0].wrapperData.Pkg
+	interfaceType := types.TypeString(layers[0].wrapperData.WrappedType, qualifier(curPkg))
+
+	arguments := []string{fmt.Sprintf("wrapped %s", interfaceType)}
+	argumentLayers := map[string]string{}
+	argumentTypes := map[string]string{}
+	layerNames := []string{}
+	for _, layer := range layers {
+		typeName := layer.wrapperData.NamedType.Obj().Name()
+		layerNames = append(layerNames, typeName)
+
+		layerArguments, err := layer.constructorArguments()
+		if err != nil {
+			return err
+		}
+		for _, field := range layerArguments {
+			if existing, ok := argumentTypes[field.Varname]; ok {
+				if existing != field.Typename {
+					return errors.Errorf(
+						"argument %s is of type %s in the %s layer, but of type %s in the %s layer",
+						field.Varname, existing, argumentLayers[field.Varname], field.Typename, typeName,
+					)
+				}
+				continue
+			}
+			argumentTypes[field.Varname] = field.Typename
+			argumentLayers[field.Varname] = typeName
+			arguments = append(arguments, field.String())
+		}
+	}
+
+	// The innermost layer has to wrap the implementation first.
+	constructions := []string{}
+	for i := len(layers) - 1; i >= 0; i-- {
+		layerArguments, err := layers[i].constructorArguments()
+		if err != nil {
+			return err
+		}
+		argumentNames := []string{"wrapped"}
+		for _, field := range layerArguments {
+			argumentNames = append(argumentNames, field.Varname)
+		}
+		constructor := layers[i].wrapperData.ConstructorName
+		if sourceData.FuncType {
+			constructor = getFuncWrapperName(layers[i].wrapperData)
+		}
+		constructions = append(constructions, fmt.Sprintf("wrapped = %s(%s)", constructor, strings.Join(argumentNames, ", ")))
+	}
+
+	decoratorName := fmt.Sprintf("Decorate%s", sourceData.NamedType.Obj().Name())
+	fmt.Fprintf(
+		w,
+		tmpl,
+		decoratorName,
+		strings.Join(layerNames, ", "),
+		decoratorName,
+		strings.Join(arguments, ", "),
+		interfaceType,
+		strings.Join(constructions, "\n"),
+	)
+
+	return nil
+}
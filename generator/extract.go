@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"io"
+	"strings"
+
+	"github.com/cube2222/StatsGenerator/analyzer"
+	"github.com/cube2222/StatsGenerator/parser"
+)
+
+// NewInterfaceGenerator creates a generator of the declaration of an interface made of the methods of the source type
+// which pass the filter.
+func NewInterfaceGenerator(sourceData *parser.SourceData, interfaceData *analyzer.WrapperTypeData, filter *MethodFilter) *InterfaceGenerator {
+	return &InterfaceGenerator{
+		sourceData:    sourceData,
+		interfaceData: interfaceData,
+		filter:        filter,
+		out:           bytes.NewBuffer(nil),
+	}
+}
+
+// InterfaceGenerator generates an interface declaration extracted from the source type,
+// so that code can depend on the interface instead of the concrete type.
+type InterfaceGenerator struct {
+	sourceData    *parser.SourceData
+	interfaceData *analyzer.WrapperTypeData
+	filter        *MethodFilter
+	out           *bytes.Buffer
+}
+
+func (g *InterfaceGenerator) Read(p []byte) (n int, err error) {
+	return g.out.Read(p)
+}
+
+func (g *InterfaceGenerator) GetBytes() []byte {
+	return g.out.Bytes()
+}
+
+func (g *InterfaceGenerator) Generate() error {
+	writePackage(g.out, g.interfaceData.Pkg)
+	writeImports(g.out, g.sourceData.Package.Imports())
+	writeExtractedInterface(g.out, g.sourceData, g.interfaceData, g.filter)
+
+	return nil
+}
+
+// Writes the declaration of the interface extracted from the source type, if there is one,
+// with the methods which pass the filter, which may be nil.
+// The doc comments of the methods are copied from the source type.
+func writeExtractedInterface(w io.Writer, sourceData *parser.SourceData, wrapperData *analyzer.WrapperTypeData, filter *MethodFilter) {
+	if wrapperData.ExtractedInterface == nil {
+		return
+	}
+	tmpl := `
+// %s is the interface of the exported methods of %s.
+type %s interface {
+	%s
+}
+`
+	curPkg := wrapperData.Pkg
+	methods := []string{}
+	for i := 0; i < sourceData.UnderlyingInterface.NumMethods(); i++ {
+		method := sourceData.UnderlyingInterface.Method(i)
+		if !filter.Instrumented(method.Name()) {
+			continue
+		}
+		if doc := sourceData.MethodDocs[method.Name()]; doc != nil {
+			for _, comment := range doc.List {
+				methods = append(methods, comment.Text)
+			}
+		}
+		signature := types.TypeString(method.Type(), qualifier(curPkg))
+		methods = append(methods, method.Name()+strings.TrimPrefix(signature, "func"))
+	}
+
+	name := wrapperData.ExtractedInterface.Obj().Name()
+	fmt.Fprintf(
+		w,
+		tmpl,
+		name,
+		types.TypeString(sourceData.SourceType, qualifier(curPkg)),
+		name,
+		strings.Join(methods, "\n"),
+	)
+}
@@ -0,0 +1,145 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"io"
+	"strings"
+
+	"github.com/cube2222/StatsGenerator/analyzer"
+	"github.com/cube2222/StatsGenerator/parser"
+)
+
+func NewFakeGenerator(sourceData *parser.SourceData, fakeData *analyzer.WrapperTypeData) *FakeGenerator {
+	return &FakeGenerator{
+		sourceData: sourceData,
+		fakeData:   fakeData,
+		out:        bytes.NewBuffer(nil),
+	}
+}
+
+// FakeGenerator generates a fake implementation of the source interface.
+// Every method of the fake records its calls, and calls the user supplied function field for the method,
+// returning zero values if it's not set.
+type FakeGenerator struct {
+	sourceData *parser.SourceData
+	fakeData   *analyzer.WrapperTypeData
+	out        *bytes.Buffer
+}
+
+func (g *FakeGenerator) Read(p []byte) (n int, err error) {
+	return g.out.Read(p)
+}
+
+func (g *FakeGenerator) GetBytes() []byte {
+	return g.out.Bytes()
+}
+
+func (g *FakeGenerator) Generate() error {
+	writePackage(g.out, g.fakeData.Pkg)
+	writeImports(g.out, g.sourceData.Package.Imports())
+	writeUserSuppliedImports(g.out, []string{"sync"})
+
+	mds := []*MethodData{}
+	signatures := []*types.Signature{}
+	for i := 0; i < g.sourceData.UnderlyingInterface.NumMethods(); i++ {
+		curMethod := g.sourceData.UnderlyingInterface.Method(i)
+
+		mds = append(mds, getMethodData(
+			g.sourceData.NamedType,
+			curMethod,
+			g.fakeData.Pkg,
+			g.fakeData.NamedType,
+			g.sourceData.MethodDocs[curMethod.Name()],
+		))
+		signatures = append(signatures, curMethod.Type().(*types.Signature))
+	}
+
+	td := getTypeData(g.sourceData.NamedType, g.fakeData)
+
+	err := checkMemberCollisions(td, mds, callMemberNames, "mu")
+	if err != nil {
+		return err
+	}
+
+	writeFakeStructure(g.out, td, mds, signatures, g.fakeData.Pkg)
+
+	for i := range mds {
+		writeCallType(g.out, td, mds[i], signatures[i], g.fakeData.Pkg)
+		writeFakeMethod(g.out, td, mds[i], signatures[i], g.fakeData)
+	}
+
+	return nil
+}
+
+func writeFakeStructure(w io.Writer, td *TypeData, mds []*MethodData, signatures []*types.Signature, curPkg *types.Package) {
+	tmpl := `
+// %s is a fake implementation of %s.
+// Its methods call the corresponding function fields, or return zero values if those aren't set.
+// The function fields should be set before the fake is used.
+type %s struct {
+	mu sync.Mutex
+	%s
+}
+
+func %s() *%s {
+	return &%s{}
+}
+`
+	fields := []string{}
+	for i, md := range mds {
+		fields = append(
+			fields,
+			fmt.Sprintf("// %sFunc is called by %s if set.", md.FunctionName, md.FunctionName),
+			fmt.Sprintf("%sFunc %s", md.FunctionName, types.TypeString(signatures[i], qualifier(curPkg))),
+			fmt.Sprintf("%sCalls []%s", lowercaseFirstLetter(md.FunctionName), callTypeName(td, md)),
+		)
+	}
+
+	fmt.Fprintf(
+		w,
+		tmpl,
+		td.TypeName,
+		td.FullOriginalTypeName,
+		td.TypeName,
+		strings.Join(fields, "\n"),
+		td.ConstructorName,
+		td.TypeName,
+		td.TypeName,
+	)
+}
+
+func writeFakeMethod(w io.Writer, td *TypeData, md *MethodData, signature *types.Signature, fakeData *analyzer.WrapperTypeData) {
+	tmpl := ` {
+	%s.mu.Lock()
+	%s.%s = append(%s.%s, %s{%s})
+	fn := %s.%sFunc
+	%s.mu.Unlock()
+
+	if fn != nil {
+		%s
+	}
+	%s
+}
+`
+	callsField := fmt.Sprintf("%sCalls", lowercaseFirstLetter(md.FunctionName))
+
+	WriteSignature(w, md, signature, fakeData.Pkg, fakeData.NamedType)
+	fmt.Fprintf(
+		w,
+		tmpl,
+		td.ReceiverVar,
+		td.ReceiverVar,
+		callsField,
+		td.ReceiverVar,
+		callsField,
+		callTypeName(td, md),
+		md.ArgumentsConnected,
+		td.ReceiverVar,
+		md.FunctionName,
+		td.ReceiverVar,
+		getFunctionFieldCall(md, signature),
+		md.ZeroValuesReturn,
+	)
+}
@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// MethodFilter decides which methods are instrumented by the template.
+// Methods which aren't are delegated directly to the wrapped implementation.
+type MethodFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// NewMethodFilter creates a filter from method names or regular expressions, which have to match the whole method name.
+// If include is empty, all methods which aren't excluded are instrumented.
+// example: NewMethodFilter(nil, []string{"Close", "String", "Ping.*"})
+func NewMethodFilter(include, exclude []string) (*MethodFilter, error) {
+	includeRegexps, err := compileMethodPatterns(include)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid include pattern")
+	}
+	excludeRegexps, err := compileMethodPatterns(exclude)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid exclude pattern")
+	}
+
+	return &MethodFilter{
+		include: includeRegexps,
+		exclude: excludeRegexps,
+	}, nil
+}
+
+// Instrumented reports whether the method with the given name passes the filter. A nil filter passes all methods.
+func (f *MethodFilter) Instrumented(name string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.include) > 0 && !matchesAny(f.include, name) {
+		return false
+	}
+	return !matchesAny(f.exclude, name)
+}
+
+func compileMethodPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	regexps := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		r, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, errors.Wrapf(err, "Couldn't compile %v", pattern)
+		}
+		regexps = append(regexps, r)
+	}
+	return regexps, nil
+}
+
+func matchesAny(regexps []*regexp.Regexp, name string) bool {
+	for _, r := range regexps {
+		if r.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
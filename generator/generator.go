@@ -3,33 +3,35 @@ package generator
 import (
 	"bytes"
 	"fmt"
+	"go/ast"
 	"go/types"
 	"strings"
 
 	"io"
 
-	"text/template"
-
 	"github.com/cube2222/StatsGenerator/analyzer"
 	"github.com/cube2222/StatsGenerator/parser"
 	"github.com/cube2222/StatsGenerator/usertemplate"
 	"github.com/pkg/errors"
 )
 
-func NewWrapperGenerator(sourceData *parser.SourceData, wrapperData *analyzer.WrapperTypeData, templateData *usertemplate.TemplateData) *WrapperGenerator {
+func NewWrapperGenerator(sourceData *parser.SourceData, wrapperData *analyzer.WrapperTypeData, templateData *usertemplate.TemplateData, filter *MethodFilter) *WrapperGenerator {
 	return &WrapperGenerator{
-		sourceData:   sourceData,
-		wrapperData:  wrapperData,
-		templateData: templateData,
-		out:          bytes.NewBuffer(nil),
+		sourceData:  sourceData,
+		wrapperData: wrapperData,
+		templates:   []*usertemplate.TemplateData{templateData},
+		filter:      filter,
+		out:         bytes.NewBuffer(nil),
 	}
 }
 
 type WrapperGenerator struct {
-	sourceData   *parser.SourceData
-	wrapperData  *analyzer.WrapperTypeData
-	templateData *usertemplate.TemplateData
-	out          *bytes.Buffer
+	sourceData  *parser.SourceData
+	wrapperData *analyzer.WrapperTypeData
+	// The templates of the wrapper, the first one being the outermost aspect. There's more than one only for merged wrappers.
+	templates []*usertemplate.TemplateData
+	filter    *MethodFilter
+	out       *bytes.Buffer
 }
 
 func (g *WrapperGenerator) Read(p []byte) (n int, err error) {
@@ -43,10 +45,39 @@ func (g *WrapperGenerator) GetBytes() []byte {
 func (g *WrapperGenerator) Generate() error {
 	writePackage(g.out, g.wrapperData.Pkg)
 	writeImports(g.out, g.sourceData.Package.Imports())
-	writeUserSuppliedImports(g.out, g.templateData.Imports)
+	writeUserSuppliedImports(g.out, g.imports())
+	writeExtractedInterface(g.out, g.sourceData, g.wrapperData, nil)
+
+	return g.writeWrapper(g.out)
+}
+
+// Writes the wrapper type, its constructor, declarations and methods.
+func (g *WrapperGenerator) writeWrapper(w io.Writer) error {
+	fields, err := mergeFields(g.templates, func(t *usertemplate.TemplateData) []usertemplate.UserSuppliedField {
+		return append(append([]usertemplate.UserSuppliedField{}, t.Fields...), t.State...)
+	})
+	if err != nil {
+		return err
+	}
+	writeStructure(w, g.wrapperData, fields)
 
-	writeStructure(g.out, g.wrapperData, g.templateData.Fields)
-	writeConstructor(g.out, g.sourceData.NamedType, g.wrapperData.Pkg, g.wrapperData.NamedType, g.templateData.Fields)
+	td := getTypeData(g.sourceData.NamedType, g.wrapperData)
+
+	err = writeConstructor(w, td, g.wrapperData.WrappedType, g.wrapperData.ResultType, g.wrapperData.Pkg, g.wrapperData.NamedType, g.templates)
+	if err != nil {
+		return err
+	}
+	for _, templateData := range g.templates {
+		if templateData.Declarations == nil {
+			continue
+		}
+		td.Params = templateData.Params
+		err := templateData.Declarations.Execute(w, td)
+		if err != nil {
+			return errors.Wrap(err, "couldn't execute declarations template")
+		}
+		fmt.Fprint(w, "\n")
+	}
 
 	for i := 0; i < g.sourceData.UnderlyingInterface.NumMethods(); i++ {
 		curMethod := g.sourceData.UnderlyingInterface.Method(i)
@@ -56,16 +87,104 @@ func (g *WrapperGenerator) Generate() error {
 			curMethod,
 			g.wrapperData.Pkg,
 			g.wrapperData.NamedType,
+			g.sourceData.MethodDocs[curMethod.Name()],
 		)
 
 		curSignature := curMethod.Type().(*types.Signature)
 
-		writeMethod(g.out, md, curSignature, g.wrapperData, g.templateData.Method)
+		if g.sourceData.FuncType {
+			// Wrapped functions are called directly, they don't have the method.
+			md.CallWrapped = fmt.Sprintf("%s.wrapped(%s)", md.ReceiverVar, getCallArguments(md, curSignature))
+			md.Next = md.CallWrapped
+		}
+
+		if md.Annotations.Has(skipAnnotation) || !g.filter.Instrumented(md.FunctionName) {
+			writeDelegatingMethod(w, md, curSignature, g.wrapperData)
+			continue
+		}
+
+		err := validateAnnotations(md, curSignature, g.templates)
+		if err != nil {
+			return err
+		}
+		err = writeMethod(w, md, curSignature, g.wrapperData, g.templates)
+		if err != nil {
+			return err
+		}
+	}
+
+	if g.sourceData.FuncType {
+		return g.writeFuncWrapper(w, td)
+	}
+
+	return nil
+}
+
+// Writes the function decorating a function of the source function type, using the method of the wrapper.
+// example: func WrapHandlerLogs(wrapped pkg.Handler, log *zap.Logger) pkg.Handler
+func (g *WrapperGenerator) writeFuncWrapper(w io.Writer, td *TypeData) error {
+	tmpl := `
+// %s decorates the function using %s.
+func %s(%s) %s {
+	return %s(%s).%s
+}
+`
+	arguments, err := g.constructorArguments()
+	if err != nil {
+		return err
 	}
+	funcType := types.TypeString(g.wrapperData.WrappedType, qualifier(g.wrapperData.Pkg))
+	parameters := []string{fmt.Sprintf("wrapped %s", funcType)}
+	argumentNames := []string{"wrapped"}
+	for _, argument := range arguments {
+		parameters = append(parameters, argument.String())
+		argumentNames = append(argumentNames, argument.Varname)
+	}
+
+	fmt.Fprintf(
+		w,
+		tmpl,
+		getFuncWrapperName(g.wrapperData),
+		td.TypeName,
+		getFuncWrapperName(g.wrapperData),
+		strings.Join(parameters, ", "),
+		funcType,
+		td.ConstructorName,
+		strings.Join(argumentNames, ", "),
+		g.sourceData.NamedType.Obj().Name(),
+	)
 
 	return nil
 }
 
+// The name of the function decorating functions of the source function type.
+// example: WrapHandlerLogs
+func getFuncWrapperName(wrapperData *analyzer.WrapperTypeData) string {
+	return fmt.Sprintf("Wrap%s", wrapperData.NamedType.Obj().Name())
+}
+
+// The imports of all the templates, without duplicates.
+func (g *WrapperGenerator) imports() []string {
+	imports := []string{}
+	seen := map[string]bool{}
+	for _, templateData := range g.templates {
+		for _, i := range templateData.Imports {
+			if !seen[i] {
+				seen[i] = true
+				imports = append(imports, i)
+			}
+		}
+	}
+	return imports
+}
+
+// The arguments of the constructor after the wrapped implementation, in order.
+func (g *WrapperGenerator) constructorArguments() ([]usertemplate.UserSuppliedField, error) {
+	return mergeFields(g.templates, func(t *usertemplate.TemplateData) []usertemplate.UserSuppliedField {
+		return append(append([]usertemplate.UserSuppliedField{}, t.Fields...), t.Arguments...)
+	})
+}
+
 func writeStructure(w io.Writer, wrapperType *analyzer.WrapperTypeData, userSuppliedFields []usertemplate.UserSuppliedField) {
 	tmpl := `type %s struct {
 	%s
@@ -77,7 +196,7 @@ func writeStructure(w io.Writer, wrapperType *analyzer.WrapperTypeData, userSupp
 	wrapperStructure := wrapperType.NamedType.Underlying().(*types.Struct)
 
 	buf := bytes.NewBuffer(nil)
-	types.WriteType(buf, wrapperStructure.Field(0).Type(), types.RelativeTo(wrapperType.Pkg))
+	types.WriteType(buf, wrapperStructure.Field(0).Type(), qualifier(wrapperType.Pkg))
 	fields = append(fields, fmt.Sprintf("%s %s", wrapperStructure.Field(0).Name(), buf.String()))
 	for _, field := range userSuppliedFields {
 		fields = append(fields, field.String())
@@ -86,25 +205,66 @@ func writeStructure(w io.Writer, wrapperType *analyzer.WrapperTypeData, userSupp
 	fmt.Fprintf(w, tmpl, wrapperType.NamedType.Obj().Name(), strings.Join(fields, "\n"))
 }
 
-func writeMethod(w io.Writer, md *MethodData, signature *types.Signature, wrapperTypeData *analyzer.WrapperTypeData, tmpl *template.Template) error {
-	WriteSignature(w, md, signature, wrapperTypeData.Pkg, wrapperTypeData.NamedType)
-	fmt.Fprint(w, " {\n")
-	err := tmpl.Execute(w, md)
-	if err != nil {
-		return errors.Wrap(err, "couldn't execute method template")
+// Writes the method, nesting the bodies of the templates so that each one's Next is the body of the following one.
+// CallWrapped is the same as Next, so that templates written for a single wrapper don't skip the following ones.
+func writeMethod(w io.Writer, md *MethodData, signature *types.Signature, wrapperTypeData *analyzer.WrapperTypeData, templates []*usertemplate.TemplateData) error {
+	body := ""
+	for i := len(templates) - 1; i >= 0; i-- {
+		aspectMd := *md
+		aspectMd.Params = templates[i].Params
+		if i < len(templates)-1 {
+			aspectMd.Next = getNestedBody(body, md, getResultsSignature(md, signature, templates[i+1]), wrapperTypeData.Pkg)
+			aspectMd.CallWrapped = aspectMd.Next
+		}
+
+		buf := bytes.NewBuffer(nil)
+		err := templates[i].GetMethodTemplate(md.FunctionName, md.Annotations).Execute(buf, &aspectMd)
+		if err != nil {
+			return errors.Wrap(err, "couldn't execute method template")
+		}
+		body = buf.String()
 	}
-	fmt.Fprint(w, "}\n")
+
+	WriteSignature(w, md, getResultsSignature(md, signature, templates[0]), wrapperTypeData.Pkg, wrapperTypeData.NamedType)
+	fmt.Fprintf(w, " {\n%s}\n", body)
 
 	return nil
 }
 
+// Returns the signature with the results named, if the template uses named results.
+func getResultsSignature(md *MethodData, signature *types.Signature, templateData *usertemplate.TemplateData) *types.Signature {
+	if !templateData.NamedResults {
+		return signature
+	}
+	results := []*types.Var{}
+	for i, result := range md.NamedResults {
+		results = append(results, types.NewVar(0, nil, result.Name, signature.Results().At(i).Type()))
+	}
+	return types.NewSignature(signature.Recv(), signature.Params(), types.NewTuple(results...), signature.Variadic())
+}
+
+// Writes a method which only calls the wrapped implementation.
+func writeDelegatingMethod(w io.Writer, md *MethodData, signature *types.Signature, wrapperTypeData *analyzer.WrapperTypeData) {
+	WriteSignature(w, md, signature, wrapperTypeData.Pkg, wrapperTypeData.NamedType)
+	if len(md.ReturnVars) == 0 {
+		fmt.Fprintf(w, " {\n%s\n}\n", md.CallWrapped)
+		return
+	}
+	fmt.Fprintf(w, " {\nreturn %s\n}\n", md.CallWrapped)
+}
+
 func writePackage(w io.Writer, pkg *types.Package) {
 	fmt.Fprintf(w, "package %s\n", pkg.Name())
 }
 
 func writeImports(w io.Writer, imports []*types.Package) {
+	written := map[string]bool{}
 	for _, i := range imports {
-		fmt.Fprintf(w, "import \"%s\"\n", i.Name())
+		if written[i.Path()] {
+			continue
+		}
+		written[i.Path()] = true
+		fmt.Fprintf(w, "import \"%s\"\n", i.Path())
 	}
 }
 
@@ -117,7 +277,7 @@ func writeUserSuppliedImports(w io.Writer, imports []string) {
 func WriteSignature(w io.Writer, md *MethodData, originalSignature *types.Signature, curPkg *types.Package, created *types.Named) {
 	receiverType := types.NewPointer(created)
 	createdTypeBuffer := bytes.NewBuffer(nil)
-	types.WriteType(createdTypeBuffer, receiverType, types.RelativeTo(curPkg))
+	types.WriteType(createdTypeBuffer, receiverType, qualifier(curPkg))
 
 	argumentVariables := []*types.Var{}
 	for i := 0; i < originalSignature.Params().Len(); i++ {
@@ -127,10 +287,10 @@ func WriteSignature(w io.Writer, md *MethodData, originalSignature *types.Signat
 	arguments := types.NewTuple(argumentVariables...)
 
 	receiver := types.NewVar(0, curPkg, md.ReceiverVar, receiverType)
-	newSignature := types.NewSignature(receiver, arguments, originalSignature.Results(), false)
+	newSignature := types.NewSignature(receiver, arguments, originalSignature.Results(), originalSignature.Variadic())
 
 	signatureBuffer := bytes.NewBuffer(nil)
-	types.WriteSignature(signatureBuffer, newSignature, types.RelativeTo(curPkg))
+	types.WriteSignature(signatureBuffer, newSignature, qualifier(curPkg))
 
 	fmt.Fprintf(
 		w,
@@ -142,40 +302,109 @@ func WriteSignature(w io.Writer, md *MethodData, originalSignature *types.Signat
 	)
 }
 
-func writeConstructor(w io.Writer, originalInterfaceType *types.Named, curPkg *types.Package, created *types.Named, userSuppliedFields []usertemplate.UserSuppliedField) {
+func writeConstructor(w io.Writer, td *TypeData, wrappedType, resultType types.Type, curPkg *types.Package, created *types.Named, templates []*usertemplate.TemplateData) error {
 	constructorTemplate := `
-func New%s(%s) %s {
-	return &%s{
+func %s(%s) %s {
+	%s := &%s{
 		%s
 	}
-}
 `
+	fields, err := mergeFields(templates, func(t *usertemplate.TemplateData) []usertemplate.UserSuppliedField {
+		return t.Fields
+	})
+	if err != nil {
+		return err
+	}
+	arguments, err := mergeFields(templates, func(t *usertemplate.TemplateData) []usertemplate.UserSuppliedField {
+		return append(append([]usertemplate.UserSuppliedField{}, t.Fields...), t.Arguments...)
+	})
+	if err != nil {
+		return err
+	}
+
 	fieldStrings := []string{
-		fmt.Sprintf("wrapped %s", originalInterfaceType),
+		fmt.Sprintf("wrapped %s", types.TypeString(wrappedType, qualifier(curPkg))),
 	}
-	for _, field := range userSuppliedFields {
+	for _, field := range arguments {
 		fieldStrings = append(fieldStrings, field.String())
 	}
 
 	initializers := []string{
 		fmt.Sprintf("wrapped: wrapped,"),
 	}
-	for _, field := range userSuppliedFields {
+	for _, field := range fields {
 		initializers = append(initializers, fmt.Sprintf("%s: %s,", field.Varname, field.Varname))
 	}
 
 	createdNameBuffer := bytes.NewBuffer(nil)
-	types.WriteType(createdNameBuffer, created, types.RelativeTo(curPkg))
+	types.WriteType(createdNameBuffer, created, qualifier(curPkg))
 
 	fmt.Fprintf(
 		w,
 		constructorTemplate,
-		createdNameBuffer,
+		td.ConstructorName,
 		strings.Join(fieldStrings, ", "),
-		originalInterfaceType,
+		types.TypeString(resultType, qualifier(curPkg)),
+		td.ReceiverVar,
 		createdNameBuffer,
 		strings.Join(initializers, "\n"),
 	)
+	for _, templateData := range templates {
+		if templateData.Constructor == nil {
+			continue
+		}
+		td.Params = templateData.Params
+		err := templateData.Constructor.Execute(w, td)
+		if err != nil {
+			return errors.Wrap(err, "couldn't execute constructor template")
+		}
+		fmt.Fprint(w, "\n")
+	}
+	fmt.Fprintf(w, "return %s\n}\n", td.ReceiverVar)
+
+	return nil
+}
+
+// TypeData is the data available to the Constructor and Declarations sections of a template.
+type TypeData struct {
+	// The name of the generated wrapper type
+	// example: MyInterfaceWrapper
+	TypeName string
+	// The name of the variable holding the wrapper. It's the type name without the initial letter capitalized
+	// example: myInterfaceWrapper
+	ReceiverVar string
+	// The name of the function constructing the wrapper
+	// example: NewMyInterfaceWrapper
+	ConstructorName string
+	// The original interface name, with the package name prepended
+	// example: pkg.MyInterface
+	FullOriginalTypeName string
+	// The original interface name, with the package name prepended, but all lowercase
+	// example: pkg.myinterface
+	LowercaseFullOriginalTypeName string
+	// The original interface name only, without the package
+	// example: MyInterface
+	ShortOriginalTypeName string
+	// The values of the template parameters, by name
+	// example: {{.Params.namespace}}
+	Params map[string]interface{}
+}
+
+func getTypeData(originalInterfaceType *types.Named, typeData *analyzer.WrapperTypeData) *TypeData {
+	td := &TypeData{}
+
+	curPkg := typeData.Pkg
+	receiverType := typeData.NamedType
+
+	td.TypeName = receiverType.Obj().Name()
+	td.ConstructorName = typeData.ConstructorName
+	td.ReceiverVar = getReceiverVariableName(receiverType, curPkg)
+
+	td.FullOriginalTypeName = getFullOriginalTypename(originalInterfaceType, curPkg)
+	td.LowercaseFullOriginalTypeName = strings.ToLower(td.FullOriginalTypeName)
+	td.ShortOriginalTypeName = originalInterfaceType.Obj().Name()
+
+	return td
 }
 
 type MethodData struct {
@@ -212,8 +441,20 @@ type MethodData struct {
 	// example: input0, input1, input2
 	ArgumentsConnected string // strings.Join(arguments, ", ")
 	// This contains the call to the wrapped function
+	// In merged wrappers, it's the same as Next for all templates but the last one
 	// example: myInterfaceWrapper.wrapped.MyFunction(input0, input1, input2)
 	CallWrapped string
+	// The results of this function with the names they have in the signature when the template enables the namedResults option.
+	// The error is named err, the other results r followed by their index
+	// example: {{range .NamedResults}}{{.Name}} {{.Type}}{{end}}
+	NamedResults []*Var
+	// The names of the named results, comma-seperated
+	// example: r0, r1, err
+	NamedResultsConnected string
+	// This contains the call to the next aspect of a merged wrapper, the body of the next template in a closure.
+	// It's the same as CallWrapped for the last template, and for wrappers using a single template
+	// example: func() (int, error) { ... }()
+	Next string
 	// This contains the set of zero variable declarations corresponding to the return variables followed by a return
 	// example:
 	// var zero0 type0
@@ -229,9 +470,48 @@ type MethodData struct {
 	// var zero1 type1
 	// return zero0, zero1,
 	ZeroValuesReturnWithoutError string
+	// The name of the first argument of type context.Context, empty if there is none
+	// example: input0
+	ContextArgument string
+	// The arguments taken by this function, with their types, see Var
+	// example: {{range .TypedArguments}}{{.Name}} {{.Type}}{{end}}
+	TypedArguments []*Var
+	// The variables returned by this function, with their types, see Var
+	// example: {{range .TypedReturnVars}}{{if isError .}}...{{end}}{{end}}
+	TypedReturnVars []*Var
+	// The annotations in the doc comment of the method, lines starting with //wrappergen:
+	// example: {{if .Annotations.Has "idempotent"}}, {{.Annotations.Get "timeout"}}, {{.Annotations.Labels}}
+	Annotations Annotations
+	// The values of the template parameters, by name
+	// example: {{.Params.namespace}}
+	Params map[string]interface{}
+}
+
+// Var is a single argument or return variable of a method.
+type Var struct {
+	// The name of the variable
+	// example: input0
+	Name string
+	// The type of the variable, as written in the generated code
+	// example: context.Context
+	Type string
+
+	goType types.Type
 }
 
-func getMethodData(originalInterfaceType *types.Named, originalFunction *types.Func, curPkg *types.Package, receiverType *types.Named) *MethodData {
+func (v *Var) String() string {
+	return v.Name
+}
+
+func (v *Var) TypeString() string {
+	return v.Type
+}
+
+func (v *Var) GoType() types.Type {
+	return v.goType
+}
+
+func getMethodData(originalInterfaceType *types.Named, originalFunction *types.Func, curPkg *types.Package, receiverType *types.Named, doc *ast.CommentGroup) *MethodData {
 	md := &MethodData{}
 
 	md.FunctionName = originalFunction.Name()
@@ -260,11 +540,23 @@ func getMethodData(originalInterfaceType *types.Named, originalFunction *types.F
 		"%s.wrapped.%s(%s)",
 		md.ReceiverVar,
 		originalFunction.Name(),
-		md.ArgumentsConnected,
+		getCallArguments(md, signature),
 	)
+	md.Next = md.CallWrapped
 
 	md.ZeroValuesReturn, md.ZeroValuesReturnWithoutError = zeroValuesReturn(signature, curPkg)
 
+	md.ContextArgument = getContextArgument(signature)
+
+	md.TypedArguments = getTypedVars(md.Arguments, signature.Params(), curPkg)
+	md.TypedReturnVars = getTypedVars(md.ReturnVars, signature.Results(), curPkg)
+
+	namedResults := getNamedResultNames(signature)
+	md.NamedResults = getTypedVars(namedResults, signature.Results(), curPkg)
+	md.NamedResultsConnected = strings.Join(namedResults, ", ")
+
+	md.Annotations = getAnnotations(doc)
+
 	return md
 }
 
@@ -278,11 +570,13 @@ func zeroValuesReturn(signature *types.Signature, curPkg *types.Package) (string
 		currentType := signature.Results().At(i).Type()
 		zeroVal := types.NewVar(0, curPkg, fmt.Sprintf("zero%d", i), currentType)
 
-		zeroValueDeclarations = append(zeroValueDeclarations, zeroVal.String())
+		zeroValueDeclaration := fmt.Sprintf("var %s %s", zeroVal.Name(), types.TypeString(currentType, qualifier(curPkg)))
+
+		zeroValueDeclarations = append(zeroValueDeclarations, zeroValueDeclaration)
 		zeroValueVariables = append(zeroValueVariables, zeroVal.Name())
 
 		if currentType.String() != "error" {
-			zeroValueDeclarationsWithoutError = append(zeroValueDeclarationsWithoutError, zeroVal.String())
+			zeroValueDeclarationsWithoutError = append(zeroValueDeclarationsWithoutError, zeroValueDeclaration)
 			zeroValueVariablesWithoutError = append(zeroValueVariablesWithoutError, zeroVal.Name())
 		}
 	}
@@ -303,6 +597,36 @@ func zeroValuesReturn(signature *types.Signature, curPkg *types.Package) (string
 	return zeroValuesReturn, zeroValuesReturnWithoutError
 }
 
+// The arguments connected, with the last one expanded if the function is variadic.
+// example: input0, input1...
+func getCallArguments(md *MethodData, signature *types.Signature) string {
+	if signature.Variadic() {
+		return md.ArgumentsConnected + "..."
+	}
+	return md.ArgumentsConnected
+}
+
+func getTypedVars(names []string, tuple *types.Tuple, curPkg *types.Package) []*Var {
+	vars := []*Var{}
+	for i := 0; i < tuple.Len(); i++ {
+		vars = append(vars, &Var{
+			Name:   names[i],
+			Type:   types.TypeString(tuple.At(i).Type(), qualifier(curPkg)),
+			goType: tuple.At(i).Type(),
+		})
+	}
+	return vars
+}
+
+func getContextArgument(signature *types.Signature) string {
+	for i := 0; i < signature.Params().Len(); i++ {
+		if types.TypeString(signature.Params().At(i).Type(), nil) == "context.Context" {
+			return signature.Params().At(i).Name()
+		}
+	}
+	return ""
+}
+
 func getArgumentNames(signature *types.Signature) []string {
 	argumentNames := []string{}
 	for i := 0; i < signature.Params().Len(); i++ {
@@ -327,9 +651,21 @@ func getReturnVarsAndCheckErrorPresent(signature *types.Signature) ([]string, bo
 	return returnVars, errorPresent
 }
 
+func getNamedResultNames(signature *types.Signature) []string {
+	names := []string{}
+	for i := 0; i < signature.Results().Len(); i++ {
+		if signature.Results().At(i).Type().String() != "error" {
+			names = append(names, fmt.Sprintf("r%d", i))
+			continue
+		}
+		names = append(names, "err")
+	}
+	return names
+}
+
 func getFullOriginalTypename(originalInterfaceType *types.Named, curPkg *types.Package) string {
 	originalTypeNameBuffer := bytes.NewBuffer(nil)
-	types.WriteType(originalTypeNameBuffer, originalInterfaceType, types.RelativeTo(curPkg))
+	types.WriteType(originalTypeNameBuffer, originalInterfaceType, qualifier(curPkg))
 	fullOriginalTypeName := originalTypeNameBuffer.String()
 
 	return fullOriginalTypeName
@@ -337,14 +673,14 @@ func getFullOriginalTypename(originalInterfaceType *types.Named, curPkg *types.P
 
 func makeSignature(curPkg *types.Package, receiverVariableName string, receiverType *types.Named, arguments *types.Tuple, originalSignature *types.Signature) *types.Signature {
 	FunctionReceiver := types.NewVar(0, curPkg, receiverVariableName, receiverType)
-	signature := types.NewSignature(FunctionReceiver, arguments, originalSignature.Results(), false)
+	signature := types.NewSignature(FunctionReceiver, arguments, originalSignature.Results(), originalSignature.Variadic())
 
 	return signature
 }
 
 func getReceiverVariableName(receiverType *types.Named, curPkg *types.Package) string {
 	receiverVarBuffer := bytes.NewBuffer(nil)
-	types.WriteType(receiverVarBuffer, receiverType, types.RelativeTo(curPkg))
+	types.WriteType(receiverVarBuffer, receiverType, qualifier(curPkg))
 
 	// Make the first letter lowercase
 	receiverName := strings.Join([]string{strings.ToLower(receiverVarBuffer.String()[0:1]), receiverVarBuffer.String()[1:]}, "")
@@ -366,3 +702,13 @@ func getArguments(originalSignature *types.Signature, curPkg *types.Package) *ty
 func getFunctionSignature(originalFunction *types.Func) *types.Signature {
 	return originalFunction.Type().(*types.Signature)
 }
+
+// Qualifies types from other packages by their package name, as opposed to types.RelativeTo, which uses the package path.
+func qualifier(curPkg *types.Package) types.Qualifier {
+	return func(other *types.Package) string {
+		if other == curPkg {
+			return ""
+		}
+		return other.Name()
+	}
+}
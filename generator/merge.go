@@ -0,0 +1,64 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+
+	"github.com/cube2222/StatsGenerator/analyzer"
+	"github.com/cube2222/StatsGenerator/parser"
+	"github.com/cube2222/StatsGenerator/usertemplate"
+	"github.com/pkg/errors"
+)
+
+// NewMergedWrapperGenerator creates a generator of a single wrapper type combining the aspects of all the templates.
+// The fields, imports and constructors of the templates are merged, while the method bodies are nested,
+// the first template being the outermost one. Each template calls the next one using Next.
+func NewMergedWrapperGenerator(sourceData *parser.SourceData, wrapperData *analyzer.WrapperTypeData, templates []*usertemplate.TemplateData, filter *MethodFilter) *WrapperGenerator {
+	return &WrapperGenerator{
+		sourceData:  sourceData,
+		wrapperData: wrapperData,
+		templates:   templates,
+		filter:      filter,
+		out:         bytes.NewBuffer(nil),
+	}
+}
+
+// Returns the union of the fields selected from each template, in order.
+// Fields with the same name are merged, unless their types differ.
+func mergeFields(templates []*usertemplate.TemplateData, selectFields func(*usertemplate.TemplateData) []usertemplate.UserSuppliedField) ([]usertemplate.UserSuppliedField, error) {
+	fields := []usertemplate.UserSuppliedField{}
+	fieldTypes := map[string]string{}
+	for _, templateData := range templates {
+		for _, field := range selectFields(templateData) {
+			if existing, ok := fieldTypes[field.Varname]; ok {
+				if existing != field.Typename {
+					return nil, errors.Errorf("field %s is declared as both %s and %s", field.Varname, existing, field.Typename)
+				}
+				continue
+			}
+			fieldTypes[field.Varname] = field.Typename
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// Wraps the method body in a closure which is called immediately, so it can be used in place of the wrapped call.
+// The arguments are passed to the closure, so the body can't change the arguments seen by the templates outside it.
+// example:
+// func(input0 context.Context, input1 int) (int, error) {
+// ...
+// }(input0, input1)
+func getNestedBody(body string, md *MethodData, signature *types.Signature, curPkg *types.Package) string {
+	params := []*types.Var{}
+	for i := 0; i < signature.Params().Len(); i++ {
+		params = append(params, types.NewVar(0, curPkg, md.Arguments[i], signature.Params().At(i).Type()))
+	}
+	closure := types.NewSignature(nil, types.NewTuple(params...), signature.Results(), signature.Variadic())
+
+	closureBuffer := bytes.NewBuffer(nil)
+	types.WriteSignature(closureBuffer, closure, qualifier(curPkg))
+
+	return fmt.Sprintf("func%s {\n%s}(%s)", closureBuffer, body, getCallArguments(md, signature))
+}
@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"bytes"
+	"go/ast"
+	"path/filepath"
+	"testing"
+
+	"github.com/cube2222/StatsGenerator/analyzer"
+	"github.com/cube2222/StatsGenerator/parser"
+	"github.com/cube2222/StatsGenerator/printer"
+	"github.com/cube2222/StatsGenerator/usertemplate"
+	"golang.org/x/tools/go/loader"
+)
+
+func TestMergedWrapperCompiles(t *testing.T) {
+	const dir = "testdata/merge"
+
+	sourceData, err := parser.ParseDirectory(dir, "Store")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The timeout template changes the context argument, which the retry template outside it uses.
+	templates := []*usertemplate.TemplateData{}
+	for _, name := range []string{"recover", "retry", "breaker", "timeout", "cache"} {
+		templateData, err := usertemplate.GetWrapperTemplate(&usertemplate.WrapperTemplateConfig{Path: name, IgnoreUnknownParams: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		templates = append(templates, templateData)
+	}
+
+	// The wrapper is generated into the package of the interface, so they can be compiled together.
+	naming := &usertemplate.TemplateData{Suffix: "Merged"}
+	wrapperTypeData := analyzer.GetWrapperTypeData(sourceData, naming, &analyzer.NameOverrides{Package: sourceData.Package.Name()}, "")
+	filter, err := NewMethodFilter(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := NewMergedWrapperGenerator(sourceData, wrapperTypeData, templates, filter)
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	generated := bytes.NewBuffer(nil)
+	if err := printer.Print(generated, g.GetBytes(), nil); err != nil {
+		t.Fatalf("couldn't print the generated code: %v\n%s", err, g.GetBytes())
+	}
+
+	filenames, err := parser.GetGoFilenames(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &loader.Config{}
+	files := []*ast.File{}
+	for _, filename := range filenames {
+		file, err := conf.ParseFile(filename, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	file, err := conf.ParseFile(filepath.Join(dir, "store_merged.go"), generated.Bytes())
+	if err != nil {
+		t.Fatalf("couldn't parse the generated code: %v\n%s", err, generated)
+	}
+	conf.CreateFromFiles("", append(files, file)...)
+
+	if _, err := conf.Load(); err != nil {
+		t.Fatalf("couldn't compile the generated code: %v\n%s", err, generated)
+	}
+}
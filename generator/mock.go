@@ -0,0 +1,336 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"io"
+	"strings"
+
+	"github.com/cube2222/StatsGenerator/analyzer"
+	"github.com/cube2222/StatsGenerator/parser"
+)
+
+func NewMockGenerator(sourceData *parser.SourceData, mockData *analyzer.WrapperTypeData) *MockGenerator {
+	return &MockGenerator{
+		sourceData: sourceData,
+		mockData:   mockData,
+		out:        bytes.NewBuffer(nil),
+	}
+}
+
+// MockGenerator generates a standalone mock implementation of the source interface.
+// Every method of the mock records its calls, and answers them using the first matching expectation,
+// falling back to the user supplied function field for the method.
+type MockGenerator struct {
+	sourceData *parser.SourceData
+	mockData   *analyzer.WrapperTypeData
+	out        *bytes.Buffer
+}
+
+func (g *MockGenerator) Read(p []byte) (n int, err error) {
+	return g.out.Read(p)
+}
+
+func (g *MockGenerator) GetBytes() []byte {
+	return g.out.Bytes()
+}
+
+func (g *MockGenerator) Generate() error {
+	writePackage(g.out, g.mockData.Pkg)
+	writeImports(g.out, g.sourceData.Package.Imports())
+	writeUserSuppliedImports(g.out, []string{"fmt", "reflect", "sync", "testing"})
+
+	mds := []*MethodData{}
+	signatures := []*types.Signature{}
+	for i := 0; i < g.sourceData.UnderlyingInterface.NumMethods(); i++ {
+		curMethod := g.sourceData.UnderlyingInterface.Method(i)
+
+		mds = append(mds, getMethodData(
+			g.sourceData.NamedType,
+			curMethod,
+			g.mockData.Pkg,
+			g.mockData.NamedType,
+			g.sourceData.MethodDocs[curMethod.Name()],
+		))
+		signatures = append(signatures, curMethod.Type().(*types.Signature))
+	}
+
+	td := getTypeData(g.sourceData.NamedType, g.mockData)
+
+	err := checkMemberCollisions(td, mds, mockMemberNames, "mu", "unexpected", "AssertExpectations")
+	if err != nil {
+		return err
+	}
+
+	writeMockStructure(g.out, td, mds, signatures, g.mockData.Pkg)
+	writeMockAssertExpectations(g.out, td, mds)
+
+	for i := range mds {
+		writeCallType(g.out, td, mds[i], signatures[i], g.mockData.Pkg)
+		writeMockExpectation(g.out, td, mds[i], signatures[i], g.mockData.Pkg)
+		writeMockMethod(g.out, td, mds[i], signatures[i], g.mockData)
+	}
+
+	return nil
+}
+
+func writeMockStructure(w io.Writer, td *TypeData, mds []*MethodData, signatures []*types.Signature, curPkg *types.Package) {
+	tmpl := `
+// %s is a mock implementation of %s.
+type %s struct {
+	mu sync.Mutex
+	unexpected []string
+	%s
+}
+
+func %s() *%s {
+	return &%s{}
+}
+`
+	fields := []string{}
+	for i, md := range mds {
+		fields = append(
+			fields,
+			fmt.Sprintf("// %sFunc is called by %s if no expectation matches the call.", md.FunctionName, md.FunctionName),
+			fmt.Sprintf("%sFunc %s", md.FunctionName, types.TypeString(signatures[i], qualifier(curPkg))),
+			fmt.Sprintf("%sCalls []%s", lowercaseFirstLetter(md.FunctionName), callTypeName(td, md)),
+			fmt.Sprintf("%sExpectations []*%s", lowercaseFirstLetter(md.FunctionName), mockExpectationTypeName(td, md)),
+		)
+	}
+
+	fmt.Fprintf(
+		w,
+		tmpl,
+		td.TypeName,
+		td.FullOriginalTypeName,
+		td.TypeName,
+		strings.Join(fields, "\n"),
+		td.ConstructorName,
+		td.TypeName,
+		td.TypeName,
+	)
+}
+
+func writeMockAssertExpectations(w io.Writer, td *TypeData, mds []*MethodData) {
+	tmpl := `
+// AssertExpectations reports an error to t for each unexpected call and for each expectation which hasn't been met.
+func (%s *%s) AssertExpectations(t testing.TB) {
+	t.Helper()
+	%s.mu.Lock()
+	defer %s.mu.Unlock()
+
+	for _, call := range %s.unexpected {
+		t.Errorf("unexpected call: %%s", call)
+	}
+	%s
+}
+`
+	checks := []string{}
+	for _, md := range mds {
+		checks = append(checks, fmt.Sprintf(
+			`for _, expectation := range %s.%sExpectations {
+				if !expectation.met() {
+					t.Errorf("unmet expectation: %%s", expectation)
+				}
+			}`,
+			td.ReceiverVar,
+			lowercaseFirstLetter(md.FunctionName),
+		))
+	}
+
+	fmt.Fprintf(
+		w,
+		tmpl,
+		td.ReceiverVar,
+		td.TypeName,
+		td.ReceiverVar,
+		td.ReceiverVar,
+		td.ReceiverVar,
+		strings.Join(checks, "\n"),
+	)
+}
+
+func writeMockExpectation(w io.Writer, td *TypeData, md *MethodData, signature *types.Signature, curPkg *types.Package) {
+	tmpl := `
+// %s describes an expected call to %s.
+type %s struct {
+	args *%s
+	%s
+	times int
+	calls int
+}
+
+// Expect%s registers and returns an expectation of a call to %s. By default it matches any arguments, any number of times.
+func (%s *%s) Expect%s() *%s {
+	%s.mu.Lock()
+	defer %s.mu.Unlock()
+	expectation := &%s{}
+	%s.%s = append(%s.%s, expectation)
+	return expectation
+}
+
+// WithArgs makes the expectation match only calls with arguments deeply equal to the given ones.
+func (expectation *%s) WithArgs(%s) *%s {
+	expectation.args = &%s{%s}
+	return expectation
+}
+
+// Return sets the values returned by calls matching the expectation.
+func (expectation *%s) Return(%s) *%s {
+	%s
+	return expectation
+}
+
+// Times makes the expectation match exactly n calls.
+func (expectation *%s) Times(n int) *%s {
+	expectation.times = n
+	return expectation
+}
+
+func (expectation *%s) matches(call %s) bool {
+	if expectation.times > 0 && expectation.calls >= expectation.times {
+		return false
+	}
+	return expectation.args == nil || reflect.DeepEqual(*expectation.args, call)
+}
+
+func (expectation *%s) met() bool {
+	if expectation.times > 0 {
+		return expectation.calls == expectation.times
+	}
+	return expectation.calls > 0
+}
+
+func (expectation *%s) String() string {
+	args := "any arguments"
+	if expectation.args != nil {
+		args = fmt.Sprintf("%%+v", *expectation.args)
+	}
+	return fmt.Sprintf("%s with %%s, times: %%d, calls: %%d", args, expectation.times, expectation.calls)
+}
+`
+	expectationTypeName := mockExpectationTypeName(td, md)
+	callTypeName := callTypeName(td, md)
+	expectationsField := fmt.Sprintf("%sExpectations", lowercaseFirstLetter(md.FunctionName))
+
+	resultFields := []string{}
+	returnParameters := []string{}
+	resultAssignments := []string{}
+	for i := 0; i < signature.Results().Len(); i++ {
+		typeString := types.TypeString(signature.Results().At(i).Type(), qualifier(curPkg))
+		resultFields = append(resultFields, fmt.Sprintf("result%d %s", i, typeString))
+		returnParameters = append(returnParameters, fmt.Sprintf("%s %s", md.ReturnVars[i], typeString))
+		resultAssignments = append(resultAssignments, fmt.Sprintf("expectation.result%d = %s", i, md.ReturnVars[i]))
+	}
+
+	fmt.Fprintf(
+		w,
+		tmpl,
+		expectationTypeName,
+		md.FunctionName,
+		expectationTypeName,
+		callTypeName,
+		strings.Join(resultFields, "\n"),
+		md.FunctionName,
+		md.FunctionName,
+		td.ReceiverVar,
+		td.TypeName,
+		md.FunctionName,
+		expectationTypeName,
+		td.ReceiverVar,
+		td.ReceiverVar,
+		expectationTypeName,
+		td.ReceiverVar,
+		expectationsField,
+		td.ReceiverVar,
+		expectationsField,
+		expectationTypeName,
+		strings.Join(getParameters(md, signature, curPkg), ", "),
+		expectationTypeName,
+		callTypeName,
+		md.ArgumentsConnected,
+		expectationTypeName,
+		strings.Join(returnParameters, ", "),
+		expectationTypeName,
+		strings.Join(resultAssignments, "\n"),
+		expectationTypeName,
+		expectationTypeName,
+		expectationTypeName,
+		callTypeName,
+		expectationTypeName,
+		expectationTypeName,
+		md.FunctionName,
+	)
+}
+
+func writeMockMethod(w io.Writer, td *TypeData, md *MethodData, signature *types.Signature, mockData *analyzer.WrapperTypeData) {
+	tmpl := ` {
+	%s.mu.Lock()
+	call := %s{%s}
+	%s.%s = append(%s.%s, call)
+	for _, expectation := range %s.%s {
+		if expectation.matches(call) {
+			expectation.calls++
+			%s.mu.Unlock()
+			return %s
+		}
+	}
+	fn := %s.%sFunc
+	if fn == nil {
+		%s.unexpected = append(%s.unexpected, fmt.Sprintf("%s%%+v", call))
+	}
+	%s.mu.Unlock()
+
+	if fn != nil {
+		%s
+	}
+	%s
+}
+`
+	callsField := fmt.Sprintf("%sCalls", lowercaseFirstLetter(md.FunctionName))
+	expectationsField := fmt.Sprintf("%sExpectations", lowercaseFirstLetter(md.FunctionName))
+
+	expectationResults := []string{}
+	for i := 0; i < signature.Results().Len(); i++ {
+		expectationResults = append(expectationResults, fmt.Sprintf("expectation.result%d", i))
+	}
+
+	WriteSignature(w, md, signature, mockData.Pkg, mockData.NamedType)
+	fmt.Fprintf(
+		w,
+		tmpl,
+		td.ReceiverVar,
+		callTypeName(td, md),
+		md.ArgumentsConnected,
+		td.ReceiverVar,
+		callsField,
+		td.ReceiverVar,
+		callsField,
+		td.ReceiverVar,
+		expectationsField,
+		td.ReceiverVar,
+		strings.Join(expectationResults, ", "),
+		td.ReceiverVar,
+		md.FunctionName,
+		td.ReceiverVar,
+		td.ReceiverVar,
+		md.FunctionName,
+		td.ReceiverVar,
+		getFunctionFieldCall(md, signature),
+		md.ZeroValuesReturn,
+	)
+}
+
+// The names of the fields and methods generated for the method by the mock.
+func mockMemberNames(md *MethodData) []string {
+	return append(
+		callMemberNames(md),
+		fmt.Sprintf("Expect%s", md.FunctionName),
+		fmt.Sprintf("%sExpectations", lowercaseFirstLetter(md.FunctionName)),
+	)
+}
+
+func mockExpectationTypeName(td *TypeData, md *MethodData) string {
+	return fmt.Sprintf("%s%sExpectation", td.TypeName, md.FunctionName)
+}
@@ -0,0 +1,12 @@
+package merge
+
+import "context"
+
+type Store interface {
+	//wrappergen:cacheable
+	Get(ctx context.Context, key string) (string, error)
+	//wrappergen:mutating
+	Put(ctx context.Context, key, value string) error
+	Keys(ctx context.Context, prefixes ...string) ([]string, error)
+	Len() int
+}
@@ -1,27 +1,57 @@
 package parser
 
 import (
+	"go/ast"
+	goparser "go/parser"
 	"go/types"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 	"golang.org/x/tools/go/loader"
 )
 
 type SourceData struct {
-	Package             *types.Package
-	NamedType           *types.Named
+	Package   *types.Package
+	NamedType *types.Named
+	// The interface of the source type. For concrete types it's made of their exported method set.
 	UnderlyingInterface *types.Interface
+	// The type of values being wrapped. It's the named type, or a pointer to it if the source is given as one.
+	// example: *pkg.Client
+	SourceType types.Type
+	// Concrete is true if the source type isn't an interface, nor a function type.
+	Concrete bool
+	// FuncType is true if the source type is a function type.
+	// Its interface then consists of a single method named like the type, with the same signature.
+	FuncType bool
+	// The doc comments of the methods, by method name. Methods without a doc comment are absent.
+	MethodDocs map[string]*ast.CommentGroup
 }
 
-func ParseDirectory(path, interfaceName string) (*SourceData, error) {
+// ParseDirectory parses the package in the directory and finds the source type in it.
+// The type name may be prefixed with a * to use a pointer to a concrete type,
+// and qualified with the name of a package imported by the parsed one.
+// example: *sql.DB
+func ParseDirectory(path, typeName string) (*SourceData, error) {
+	sourceData, err := ParseDirectoryTypes(path, typeName)
+	if err != nil {
+		return nil, err
+	}
+	return sourceData[0], nil
+}
+
+// ParseDirectoryTypes is like ParseDirectory, but finds multiple types, in order.
+// As the package is parsed only once, the types they use can be compared.
+func ParseDirectoryTypes(path string, typeNames ...string) ([]*SourceData, error) {
 	filenames, err := GetGoFilenames(path)
 	if err != nil {
 		return nil, errors.Wrap(err, "Couldn't get *.go filenames")
 	}
 
-	conf := &loader.Config{}
+	conf := &loader.Config{
+		ParserMode: goparser.ParseComments,
+	}
 	conf.CreateFromFilenames("", filenames...)
 
 	program, err := conf.Load()
@@ -32,28 +62,204 @@ func ParseDirectory(path, interfaceName string) (*SourceData, error) {
 	if len(program.Created) == 0 {
 		return nil, errors.Errorf("No package to parse")
 	}
+
+	sourceData := []*SourceData{}
+	for _, typeName := range typeNames {
+		data, err := getSourceData(program, typeName)
+		if err != nil {
+			return nil, err
+		}
+		sourceData = append(sourceData, data)
+	}
+
+	return sourceData, nil
+}
+
+func getSourceData(program *loader.Program, typeName string) (*SourceData, error) {
 	pkg := program.Created[0].Pkg
 
-	object := pkg.Scope().Lookup(interfaceName)
+	pointer := strings.HasPrefix(typeName, "*")
+	name := strings.TrimPrefix(typeName, "*")
+	typePkg := pkg
+	if index := strings.LastIndex(name, "."); index != -1 {
+		typePkg = getImportedPackage(pkg, name[:index])
+		if typePkg == nil {
+			return nil, errors.Errorf("Couldn't find imported package %v", name[:index])
+		}
+		name = name[index+1:]
+	}
+
+	object := typePkg.Scope().Lookup(name)
 	if object == nil {
-		return nil, errors.Errorf("Couldn't find object for interface called %v", interfaceName)
+		return nil, errors.Errorf("Couldn't find object for type called %v", typeName)
 	}
 	named, ok := object.Type().(*types.Named)
 	if !ok {
-		return nil, errors.Errorf("%v is not a valid type", interfaceName)
+		return nil, errors.Errorf("%v is not a valid type", typeName)
 	}
-	iface, ok := named.Underlying().(*types.Interface)
-	if !ok {
-		return nil, errors.Errorf("%v is not an interface", interfaceName)
+	var files []*ast.File
+	if info := program.Package(typePkg.Path()); info != nil {
+		files = info.Files
+	}
+
+	if iface, ok := named.Underlying().(*types.Interface); ok {
+		if pointer {
+			return nil, errors.Errorf("%v is a pointer to an interface", typeName)
+		}
+		return &SourceData{
+			Package:             pkg,
+			NamedType:           named,
+			UnderlyingInterface: iface,
+			SourceType:          named,
+			MethodDocs:          getMethodDocs(files, name),
+		}, nil
+	}
+
+	if signature, ok := named.Underlying().(*types.Signature); ok {
+		if pointer {
+			return nil, errors.Errorf("%v is a pointer to a function type", typeName)
+		}
+		method := types.NewFunc(
+			named.Obj().Pos(),
+			named.Obj().Pkg(),
+			name,
+			types.NewSignature(nil, signature.Params(), signature.Results(), signature.Variadic()),
+		)
+		return &SourceData{
+			Package:             pkg,
+			NamedType:           named,
+			UnderlyingInterface: types.NewInterfaceType([]*types.Func{method}, nil).Complete(),
+			SourceType:          named,
+			FuncType:            true,
+			MethodDocs:          getFuncTypeDocs(files, name),
+		}, nil
+	}
+
+	var sourceType types.Type = named
+	if pointer {
+		sourceType = types.NewPointer(named)
 	}
 
 	return &SourceData{
 		Package:             pkg,
 		NamedType:           named,
-		UnderlyingInterface: iface,
+		UnderlyingInterface: getMethodSetInterface(sourceType),
+		SourceType:          sourceType,
+		Concrete:            true,
+		MethodDocs:          getConcreteMethodDocs(files, name),
 	}, nil
 }
 
+func getImportedPackage(pkg *types.Package, name string) *types.Package {
+	for _, imported := range pkg.Imports() {
+		if imported.Name() == name {
+			return imported
+		}
+	}
+	return nil
+}
+
+// Returns an interface consisting of the exported methods in the method set of the type.
+func getMethodSetInterface(t types.Type) *types.Interface {
+	methodSet := types.NewMethodSet(t)
+	methods := []*types.Func{}
+	for i := 0; i < methodSet.Len(); i++ {
+		method := methodSet.At(i).Obj().(*types.Func)
+		if !method.Exported() {
+			continue
+		}
+		signature := method.Type().(*types.Signature)
+		methods = append(methods, types.NewFunc(
+			method.Pos(),
+			method.Pkg(),
+			method.Name(),
+			types.NewSignature(nil, signature.Params(), signature.Results(), signature.Variadic()),
+		))
+	}
+	return types.NewInterfaceType(methods, nil).Complete()
+}
+
+func getMethodDocs(files []*ast.File, interfaceName string) map[string]*ast.CommentGroup {
+	docs := map[string]*ast.CommentGroup{}
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			spec, ok := node.(*ast.TypeSpec)
+			if !ok || spec.Name.Name != interfaceName {
+				return true
+			}
+			iface, ok := spec.Type.(*ast.InterfaceType)
+			if !ok {
+				return false
+			}
+			for _, method := range iface.Methods.List {
+				if method.Doc == nil {
+					continue
+				}
+				for _, name := range method.Names {
+					docs[name.Name] = method.Doc
+				}
+			}
+			return false
+		})
+	}
+	return docs
+}
+
+// Returns the doc comment of the function type, by its name, as it's also the name of its only method.
+func getFuncTypeDocs(files []*ast.File, typeName string) map[string]*ast.CommentGroup {
+	docs := map[string]*ast.CommentGroup{}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok || typeSpec.Name.Name != typeName {
+					continue
+				}
+				// The doc of an ungrouped declaration is attached to the declaration, not the spec.
+				doc := typeSpec.Doc
+				if doc == nil && len(genDecl.Specs) == 1 {
+					doc = genDecl.Doc
+				}
+				if doc != nil {
+					docs[typeName] = doc
+				}
+			}
+		}
+	}
+	return docs
+}
+
+// Returns the doc comments of the methods declared on the concrete type, by method name.
+func getConcreteMethodDocs(files []*ast.File, typeName string) map[string]*ast.CommentGroup {
+	docs := map[string]*ast.CommentGroup{}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Recv == nil || funcDecl.Doc == nil || len(funcDecl.Recv.List) == 0 {
+				continue
+			}
+			if getReceiverTypeName(funcDecl.Recv.List[0].Type) == typeName {
+				docs[funcDecl.Name.Name] = funcDecl.Doc
+			}
+		}
+	}
+	return docs
+}
+
+func getReceiverTypeName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return getReceiverTypeName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
+
 func GetGoFilenames(path string) ([]string, error) {
 	files, err := ioutil.ReadDir(path)
 	if err != nil {
@@ -63,8 +269,9 @@ func GetGoFilenames(path string) ([]string, error) {
 	filenames := make([]string, 0, len(files))
 
 	for _, f := range files {
-		if f.IsDir() == false && filepath.Ext(f.Name()) == ".go" {
-			filenames = append(filenames, f.Name())
+		// Empty files are skipped, like the output file, which is created before parsing.
+		if f.IsDir() == false && filepath.Ext(f.Name()) == ".go" && f.Size() > 0 {
+			filenames = append(filenames, filepath.Join(path, f.Name()))
 		}
 	}
 
@@ -0,0 +1,56 @@
+package runtime
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff computes exponentially growing delays between attempts, with full jitter.
+type Backoff struct {
+	// The maximum delay after the first attempt.
+	Base time.Duration
+	// The maximum delay after any attempt. Zero or less means the delays aren't capped.
+	Max time.Duration
+}
+
+// Delay returns the delay after the given attempt, counted from 1.
+// It's a random duration up to the base doubled after each attempt, capped at the maximum if there is one.
+func (b Backoff) Delay(attempt int) time.Duration {
+	limit := b.Base
+	for i := 1; i < attempt && (b.Max <= 0 || limit < b.Max); i++ {
+		if limit > math.MaxInt64/2 {
+			limit = math.MaxInt64
+			break
+		}
+		limit *= 2
+	}
+	if b.Max > 0 && limit > b.Max {
+		limit = b.Max
+	}
+	if limit <= 0 {
+		return 0
+	}
+	if limit == math.MaxInt64 {
+		return time.Duration(rand.Int63())
+	}
+	return time.Duration(rand.Int63n(int64(limit) + 1))
+}
+
+// Sleep waits for the duration, or until the context is done, in which case it returns false.
+// The context may be nil.
+func Sleep(ctx context.Context, d time.Duration) bool {
+	if ctx == nil {
+		time.Sleep(d)
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
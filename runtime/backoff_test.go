@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		attempt int
+		// The delay has to be at most the limit, and is expected to reach at least half of it in some of the runs.
+		limit time.Duration
+	}{
+		{
+			name:    "first attempt",
+			backoff: Backoff{Base: time.Second, Max: time.Minute},
+			attempt: 1,
+			limit:   time.Second,
+		},
+		{
+			name:    "doubled",
+			backoff: Backoff{Base: time.Second, Max: time.Minute},
+			attempt: 3,
+			limit:   4 * time.Second,
+		},
+		{
+			name:    "capped",
+			backoff: Backoff{Base: time.Second, Max: 3 * time.Second},
+			attempt: 10,
+			limit:   3 * time.Second,
+		},
+		{
+			name:    "no cap",
+			backoff: Backoff{Base: time.Second},
+			attempt: 10,
+			limit:   512 * time.Second,
+		},
+		{
+			name:    "no cap without overflow",
+			backoff: Backoff{Base: time.Second},
+			attempt: 1000,
+			limit:   math.MaxInt64,
+		},
+		{
+			name:    "no base",
+			backoff: Backoff{Max: time.Second},
+			attempt: 3,
+			limit:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached := tt.limit == 0
+			for i := 0; i < 100; i++ {
+				delay := tt.backoff.Delay(tt.attempt)
+				if delay < 0 || delay > tt.limit {
+					t.Fatalf("expected a delay up to %v, got %v", tt.limit, delay)
+				}
+				reached = reached || delay >= tt.limit/2
+			}
+			if !reached {
+				t.Fatalf("expected delays of at least %v", tt.limit/2)
+			}
+		})
+	}
+}
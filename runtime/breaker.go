@@ -0,0 +1,183 @@
+package runtime
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen is returned by Breaker.Allow while the breaker is open.
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a Breaker.
+type BreakerState int
+
+const (
+	// Calls are allowed.
+	BreakerClosed BreakerState = iota
+	// Calls are rejected until the cool-down passes.
+	BreakerOpen
+	// A single trial call is allowed, which closes the breaker if it succeeds, and opens it again otherwise.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// Breaker is a circuit breaker. It opens after a number of consecutive failures,
+// and lets a trial call through once the cool-down passes.
+type Breaker struct {
+	failureThreshold int
+	coolDown         time.Duration
+	onStateChange    func(from, to BreakerState)
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+	// Incremented on every state change, so that results of calls allowed in an earlier state are ignored.
+	generation uint64
+}
+
+// BreakerToken is returned by Breaker.Allow for an allowed call, and identifies the state the call was allowed in.
+type BreakerToken struct {
+	generation uint64
+	trial      bool
+}
+
+// NewBreaker creates a closed breaker. onStateChange may be nil.
+// It's called with the breaker locked, so it mustn't use the breaker.
+func NewBreaker(failureThreshold int, coolDown time.Duration, onStateChange func(from, to BreakerState)) *Breaker {
+	return &Breaker{
+		failureThreshold: failureThreshold,
+		coolDown:         coolDown,
+		onStateChange:    onStateChange,
+	}
+}
+
+// Allow returns ErrBreakerOpen if a call isn't allowed.
+// Allowed calls have to be followed by Done, with the returned token.
+func (b *Breaker) Allow() (BreakerToken, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.coolDown {
+			return BreakerToken{}, ErrBreakerOpen
+		}
+		b.setState(BreakerHalfOpen)
+		b.trial = true
+		return BreakerToken{generation: b.generation, trial: true}, nil
+	case BreakerHalfOpen:
+		if b.trial {
+			return BreakerToken{}, ErrBreakerOpen
+		}
+		b.trial = true
+		return BreakerToken{generation: b.generation, trial: true}, nil
+	}
+	return BreakerToken{generation: b.generation}, nil
+}
+
+// Done records the result of an allowed call.
+// Results of calls allowed before the last state change are ignored,
+// so that only the trial call decides whether a half-open breaker closes.
+func (b *Breaker) Done(token BreakerToken, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if token.generation != b.generation {
+		return
+	}
+
+	if token.trial {
+		b.trial = false
+		if err == nil {
+			b.failures = 0
+			b.setState(BreakerClosed)
+		} else {
+			b.open()
+		}
+		return
+	}
+
+	if err == nil {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.failureThreshold {
+		b.open()
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *Breaker) open() {
+	b.openedAt = time.Now()
+	b.setState(BreakerOpen)
+}
+
+func (b *Breaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	from := b.state
+	b.state = state
+	b.generation++
+	if b.onStateChange != nil {
+		b.onStateChange(from, state)
+	}
+}
+
+// BreakerGroup holds a breaker per key, like a method name, created on first use.
+type BreakerGroup struct {
+	failureThreshold int
+	coolDown         time.Duration
+	onStateChange    func(key string, from, to BreakerState)
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewBreakerGroup creates a group of breakers with the same settings. onStateChange may be nil.
+func NewBreakerGroup(failureThreshold int, coolDown time.Duration, onStateChange func(key string, from, to BreakerState)) *BreakerGroup {
+	return &BreakerGroup{
+		failureThreshold: failureThreshold,
+		coolDown:         coolDown,
+		onStateChange:    onStateChange,
+		breakers:         map[string]*Breaker{},
+	}
+}
+
+// Get returns the breaker for the key.
+func (g *BreakerGroup) Get(key string) *Breaker {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	breaker, ok := g.breakers[key]
+	if !ok {
+		var onStateChange func(from, to BreakerState)
+		if g.onStateChange != nil {
+			onStateChange = func(from, to BreakerState) {
+				g.onStateChange(key, from, to)
+			}
+		}
+		breaker = NewBreaker(g.failureThreshold, g.coolDown, onStateChange)
+		g.breakers[key] = breaker
+	}
+	return breaker
+}
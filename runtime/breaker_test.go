@@ -0,0 +1,225 @@
+package runtime
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errCall = errors.New("call failed")
+
+// A step of a breaker test, which allows a call, expects a call to be rejected, or finishes an allowed call.
+type breakerStep struct {
+	action string
+	// The name of the allowed call, whose token is kept for finishing it.
+	call string
+	err  error
+	// The state of the breaker after the step.
+	state BreakerState
+}
+
+func allow(call string, state BreakerState) breakerStep {
+	return breakerStep{action: "allow", call: call, state: state}
+}
+
+func reject(state BreakerState) breakerStep {
+	return breakerStep{action: "reject", state: state}
+}
+
+func done(call string, err error, state BreakerState) breakerStep {
+	return breakerStep{action: "done", call: call, err: err, state: state}
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	tests := []struct {
+		name             string
+		failureThreshold int
+		coolDown         time.Duration
+		steps            []breakerStep
+	}{
+		{
+			name:             "opens after consecutive failures",
+			failureThreshold: 2,
+			coolDown:         time.Hour,
+			steps: []breakerStep{
+				allow("a", BreakerClosed),
+				done("a", errCall, BreakerClosed),
+				allow("b", BreakerClosed),
+				done("b", errCall, BreakerOpen),
+				reject(BreakerOpen),
+			},
+		},
+		{
+			name:             "success resets failures",
+			failureThreshold: 2,
+			coolDown:         time.Hour,
+			steps: []breakerStep{
+				allow("a", BreakerClosed),
+				done("a", errCall, BreakerClosed),
+				allow("b", BreakerClosed),
+				done("b", nil, BreakerClosed),
+				allow("c", BreakerClosed),
+				done("c", errCall, BreakerClosed),
+			},
+		},
+		{
+			name:             "successful trial closes",
+			failureThreshold: 1,
+			steps: []breakerStep{
+				allow("a", BreakerClosed),
+				done("a", errCall, BreakerOpen),
+				allow("trial", BreakerHalfOpen),
+				reject(BreakerHalfOpen),
+				done("trial", nil, BreakerClosed),
+				allow("b", BreakerClosed),
+			},
+		},
+		{
+			name:             "failed trial opens again",
+			failureThreshold: 3,
+			steps: []breakerStep{
+				allow("a", BreakerClosed),
+				allow("b", BreakerClosed),
+				allow("c", BreakerClosed),
+				done("a", errCall, BreakerClosed),
+				done("b", errCall, BreakerClosed),
+				done("c", errCall, BreakerOpen),
+				allow("trial", BreakerHalfOpen),
+				done("trial", errCall, BreakerOpen),
+			},
+		},
+		{
+			name:             "call allowed while closed doesn't close half-open breaker",
+			failureThreshold: 1,
+			steps: []breakerStep{
+				allow("slow", BreakerClosed),
+				allow("a", BreakerClosed),
+				done("a", errCall, BreakerOpen),
+				allow("trial", BreakerHalfOpen),
+				done("slow", nil, BreakerHalfOpen),
+				reject(BreakerHalfOpen),
+				done("trial", nil, BreakerClosed),
+			},
+		},
+		{
+			name:             "call allowed while closed doesn't fail half-open breaker",
+			failureThreshold: 1,
+			steps: []breakerStep{
+				allow("slow", BreakerClosed),
+				allow("a", BreakerClosed),
+				done("a", errCall, BreakerOpen),
+				allow("trial", BreakerHalfOpen),
+				done("slow", errCall, BreakerHalfOpen),
+				done("trial", nil, BreakerClosed),
+			},
+		},
+		{
+			name:             "call allowed before opening doesn't count after closing",
+			failureThreshold: 1,
+			steps: []breakerStep{
+				allow("slow", BreakerClosed),
+				allow("a", BreakerClosed),
+				done("a", errCall, BreakerOpen),
+				allow("trial", BreakerHalfOpen),
+				done("trial", nil, BreakerClosed),
+				done("slow", errCall, BreakerClosed),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBreaker(tt.failureThreshold, tt.coolDown, nil)
+			tokens := map[string]BreakerToken{}
+			for i, step := range tt.steps {
+				switch step.action {
+				case "allow":
+					token, err := b.Allow()
+					if err != nil {
+						t.Fatalf("step %d: call %s not allowed: %v", i, step.call, err)
+					}
+					tokens[step.call] = token
+				case "reject":
+					if _, err := b.Allow(); err != ErrBreakerOpen {
+						t.Fatalf("step %d: expected ErrBreakerOpen, got %v", i, err)
+					}
+				case "done":
+					b.Done(tokens[step.call], step.err)
+				}
+				if state := b.State(); state != step.state {
+					t.Fatalf("step %d: expected state %v, got %v", i, step.state, state)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerConcurrentCalls(t *testing.T) {
+	var mu sync.Mutex
+	transitions := []BreakerState{BreakerClosed}
+	b := NewBreaker(3, time.Millisecond, func(from, to BreakerState) {
+		mu.Lock()
+		defer mu.Unlock()
+		transitions = append(transitions, to)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				token, err := b.Allow()
+				if err != nil {
+					continue
+				}
+				var callErr error
+				if (i+j)%3 == 0 {
+					callErr = errCall
+				}
+				b.Done(token, callErr)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	valid := map[BreakerState][]BreakerState{
+		BreakerClosed:   {BreakerOpen},
+		BreakerOpen:     {BreakerHalfOpen},
+		BreakerHalfOpen: {BreakerClosed, BreakerOpen},
+	}
+	for i := 1; i < len(transitions); i++ {
+		from, to := transitions[i-1], transitions[i]
+		ok := false
+		for _, next := range valid[from] {
+			ok = ok || next == to
+		}
+		if !ok {
+			t.Fatalf("invalid transition %d from %v to %v", i, from, to)
+		}
+	}
+}
+
+func TestBreakerGroup(t *testing.T) {
+	changed := map[string]int{}
+	g := NewBreakerGroup(1, time.Hour, func(key string, from, to BreakerState) {
+		changed[key]++
+	})
+
+	token, err := g.Get("Get").Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Get("Get").Done(token, errCall)
+
+	if state := g.Get("Get").State(); state != BreakerOpen {
+		t.Errorf("expected the Get breaker to be open, got %v", state)
+	}
+	if state := g.Get("Put").State(); state != BreakerClosed {
+		t.Errorf("expected the Put breaker to be closed, got %v", state)
+	}
+	if changed["Get"] != 1 || changed["Put"] != 0 {
+		t.Errorf("unexpected state changes %v", changed)
+	}
+}
@@ -0,0 +1,113 @@
+package runtime
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache is a cache of call results, whose entries expire after a TTL.
+// When it's full, the least recently used entry is evicted.
+type Cache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[interface{}]*list.Element
+	// The entries from the most to the least recently used.
+	recent *list.List
+	// Incremented by Clear, see SetInGeneration.
+	generation uint64
+}
+
+type cacheEntry struct {
+	key       interface{}
+	value     interface{}
+	expiresAt time.Time
+}
+
+// NewCache creates an empty cache. A ttl or maxEntries of zero or less disables expiration or the size bound respectively.
+func NewCache(ttl time.Duration, maxEntries int) *Cache {
+	return &Cache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    map[interface{}]*list.Element{},
+		recent:     list.New(),
+	}
+}
+
+// Get returns the value cached under the key, if there is one which hasn't expired. Keys have to be comparable.
+func (c *Cache) Get(key interface{}) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if c.ttl > 0 && time.Now().After(entry.expiresAt) {
+		c.remove(element)
+		return nil, false
+	}
+	c.recent.MoveToFront(element)
+	return entry.value, true
+}
+
+// Set caches the value under the key, evicting the least recently used entry if the cache is full.
+func (c *Cache) Set(key, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, value)
+}
+
+func (c *Cache) set(key, value interface{}) {
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	c.entries[key] = c.recent.PushFront(&cacheEntry{
+		key:       key,
+		value:     value,
+		expiresAt: time.Now().Add(c.ttl),
+	})
+	if c.maxEntries > 0 && c.recent.Len() > c.maxEntries {
+		c.remove(c.recent.Back())
+	}
+}
+
+// Generation returns the current generation of the cache, which changes every time it's cleared.
+// Take it before making a call whose results will be cached, and pass it to SetInGeneration.
+func (c *Cache) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// SetInGeneration caches the value like Set, unless the cache has been cleared since the generation was taken,
+// as the value may then be stale. Reports whether the value was cached.
+func (c *Cache) SetInGeneration(generation uint64, key, value interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != generation {
+		return false
+	}
+	c.set(key, value)
+	return true
+}
+
+// Clear removes all entries.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = map[interface{}]*list.Element{}
+	c.recent.Init()
+	c.generation++
+}
+
+func (c *Cache) remove(element *list.Element) {
+	delete(c.entries, element.Value.(*cacheEntry).key)
+	c.recent.Remove(element)
+}
@@ -0,0 +1,156 @@
+package runtime
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	type step struct {
+		// Set the key to the value if set is true, otherwise get it, expecting the value, or no value if it's nil.
+		set   bool
+		key   string
+		value interface{}
+		// Sleep before the step.
+		sleep time.Duration
+		clear bool
+	}
+	tests := []struct {
+		name       string
+		ttl        time.Duration
+		maxEntries int
+		steps      []step
+	}{
+		{
+			name: "get set values",
+			steps: []step{
+				{key: "a"},
+				{set: true, key: "a", value: 1},
+				{set: true, key: "b", value: 2},
+				{key: "a", value: 1},
+				{key: "b", value: 2},
+				{set: true, key: "a", value: 3},
+				{key: "a", value: 3},
+			},
+		},
+		{
+			name:       "evicts least recently used",
+			maxEntries: 2,
+			steps: []step{
+				{set: true, key: "a", value: 1},
+				{set: true, key: "b", value: 2},
+				{key: "a", value: 1},
+				{set: true, key: "c", value: 3},
+				{key: "b"},
+				{key: "a", value: 1},
+				{key: "c", value: 3},
+			},
+		},
+		{
+			name: "expires",
+			ttl:  10 * time.Millisecond,
+			steps: []step{
+				{set: true, key: "a", value: 1},
+				{key: "a", value: 1},
+				{sleep: 20 * time.Millisecond, key: "a"},
+			},
+		},
+		{
+			name: "clear",
+			steps: []step{
+				{set: true, key: "a", value: 1},
+				{clear: true},
+				{key: "a"},
+				{set: true, key: "a", value: 2},
+				{key: "a", value: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache(tt.ttl, tt.maxEntries)
+			for i, step := range tt.steps {
+				time.Sleep(step.sleep)
+				switch {
+				case step.clear:
+					c.Clear()
+				case step.set:
+					c.Set(step.key, step.value)
+				default:
+					value, ok := c.Get(step.key)
+					if ok != (step.value != nil) || value != step.value {
+						t.Fatalf("step %d: expected %v, got %v, %v", i, step.value, value, ok)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCacheSetInGeneration(t *testing.T) {
+	c := NewCache(0, 0)
+
+	generation := c.Generation()
+	if !c.SetInGeneration(generation, "a", 1) {
+		t.Fatal("expected the value to be cached")
+	}
+
+	// A call which started before the cache was cleared finishes after it.
+	stale := c.Generation()
+	c.Clear()
+	if c.SetInGeneration(stale, "a", 2) {
+		t.Fatal("expected the stale value not to be cached")
+	}
+	if value, ok := c.Get("a"); ok {
+		t.Fatalf("expected no value, got %v", value)
+	}
+
+	if !c.SetInGeneration(c.Generation(), "a", 3) {
+		t.Fatal("expected the value to be cached")
+	}
+	if value, _ := c.Get("a"); value != 3 {
+		t.Fatalf("expected 3, got %v", value)
+	}
+}
+
+func TestCacheConcurrentInvalidation(t *testing.T) {
+	c := NewCache(time.Minute, 8)
+
+	// The value stored under the key is the version of the data it was read at,
+	// so a value older than the version at the time of the last Clear is stale.
+	var mu sync.Mutex
+	version := 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				generation := c.Generation()
+				mu.Lock()
+				read := version
+				mu.Unlock()
+				c.SetInGeneration(generation, j%4, read)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				mu.Lock()
+				version++
+				mu.Unlock()
+				c.Clear()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for key := 0; key < 4; key++ {
+		if value, ok := c.Get(key); ok && value.(int) != version {
+			t.Fatalf("key %d holds stale version %v, the current one is %d", key, value, version)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrLimited is returned by default when a call is rejected by a Limiter.
+var ErrLimited = errors.New("call limit exceeded")
+
+// Limiter limits the rate of calls using a token bucket, and the number of calls in flight using a semaphore.
+type Limiter struct {
+	rate  *rate.Limiter
+	slots chan struct{}
+}
+
+// NewLimiter creates a limiter allowing limit calls per second with the given burst, and maxInFlight concurrent calls.
+// A limit or maxInFlight of zero or less disables the respective limit.
+func NewLimiter(limit rate.Limit, burst, maxInFlight int) *Limiter {
+	l := &Limiter{}
+	if limit > 0 {
+		l.rate = rate.NewLimiter(limit, burst)
+	}
+	if maxInFlight > 0 {
+		l.slots = make(chan struct{}, maxInFlight)
+	}
+	return l
+}
+
+// Wait blocks until the call is allowed, or the context is done.
+// If it returns nil, Release has to be called once the call finishes.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if l.rate != nil {
+		if err := l.rate.Wait(ctx); err != nil {
+			l.Release()
+			return err
+		}
+	}
+	return nil
+}
+
+// TryAcquire reports whether the call is allowed right away, without waiting.
+// If it returns true, Release has to be called once the call finishes.
+func (l *Limiter) TryAcquire() bool {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	if l.rate != nil && !l.rate.Allow() {
+		l.Release()
+		return false
+	}
+	return true
+}
+
+// Release frees the slot of a finished call.
+func (l *Limiter) Release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// LimiterGroup holds a limiter per key, like a method name, created on first use.
+type LimiterGroup struct {
+	limit       rate.Limit
+	burst       int
+	maxInFlight int
+
+	mu       sync.Mutex
+	limiters map[string]*Limiter
+}
+
+// NewLimiterGroup creates a group of limiters with the same settings, see NewLimiter.
+func NewLimiterGroup(limit rate.Limit, burst, maxInFlight int) *LimiterGroup {
+	return &LimiterGroup{
+		limit:       limit,
+		burst:       burst,
+		maxInFlight: maxInFlight,
+		limiters:    map[string]*Limiter{},
+	}
+}
+
+// Get returns the limiter for the key.
+func (g *LimiterGroup) Get(key string) *Limiter {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	limiter, ok := g.limiters[key]
+	if !ok {
+		limiter = NewLimiter(g.limit, g.burst, g.maxInFlight)
+		g.limiters[key] = limiter
+	}
+	return limiter
+}
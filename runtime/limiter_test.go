@@ -0,0 +1,164 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestLimiterTryAcquire(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       rate.Limit
+		burst       int
+		maxInFlight int
+		// Whether to acquire, or to release, at each step.
+		steps []bool
+		// The results of TryAcquire, for the acquiring steps.
+		expected []bool
+	}{
+		{
+			name:     "unlimited",
+			steps:    []bool{true, true, true},
+			expected: []bool{true, true, true},
+		},
+		{
+			name:        "in flight limit",
+			maxInFlight: 2,
+			steps:       []bool{true, true, true},
+			expected:    []bool{true, true, false},
+		},
+		{
+			name:        "release frees a slot",
+			maxInFlight: 1,
+			steps:       []bool{true, true, false, true},
+			expected:    []bool{true, false, true},
+		},
+		{
+			name:     "rate limit",
+			limit:    rate.Every(time.Hour),
+			burst:    2,
+			steps:    []bool{true, true, true},
+			expected: []bool{true, true, false},
+		},
+		{
+			name:        "rejection by rate releases the slot",
+			limit:       rate.Every(time.Hour),
+			burst:       1,
+			maxInFlight: 1,
+			steps:       []bool{true, false, true, true},
+			expected:    []bool{true, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter(tt.limit, tt.burst, tt.maxInFlight)
+			results := []bool{}
+			inFlight := 0
+			for _, acquire := range tt.steps {
+				if !acquire {
+					l.Release()
+					inFlight--
+					continue
+				}
+				acquired := l.TryAcquire()
+				if acquired {
+					inFlight++
+				}
+				results = append(results, acquired)
+			}
+			for i := range tt.expected {
+				if results[i] != tt.expected[i] {
+					t.Fatalf("expected %v, got %v", tt.expected, results)
+				}
+			}
+			if tt.maxInFlight > 0 && len(l.slots) != inFlight {
+				t.Fatalf("expected %d slots taken, got %d", inFlight, len(l.slots))
+			}
+		})
+	}
+}
+
+func TestLimiterWaitReleasesOnCancel(t *testing.T) {
+	l := NewLimiter(rate.Every(time.Hour), 1, 1)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	l.Release()
+
+	// The slot is free, but the rate limit can't be satisfied before the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err == nil {
+		t.Fatal("expected an error waiting for the rate limit")
+	}
+	if len(l.slots) != 0 {
+		t.Fatalf("expected the slot to be released, %d taken", len(l.slots))
+	}
+
+	// All slots are taken.
+	l = NewLimiter(0, 0, 1)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(canceled); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(l.slots) != 1 {
+		t.Fatalf("expected only the first call to take a slot, %d taken", len(l.slots))
+	}
+}
+
+func TestLimiterConcurrentCalls(t *testing.T) {
+	const maxInFlight = 3
+	l := NewLimiterGroup(0, 0, maxInFlight).Get("")
+
+	var inFlight, maxSeen int32
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if err := l.Wait(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+				current := atomic.AddInt32(&inFlight, 1)
+				for {
+					seen := atomic.LoadInt32(&maxSeen)
+					if current <= seen || atomic.CompareAndSwapInt32(&maxSeen, seen, current) {
+						break
+					}
+				}
+				atomic.AddInt32(&inFlight, -1)
+				l.Release()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxSeen > maxInFlight {
+		t.Fatalf("expected at most %d calls in flight, got %d", maxInFlight, maxSeen)
+	}
+	if len(l.slots) != 0 {
+		t.Fatalf("expected all slots to be released, %d taken", len(l.slots))
+	}
+}
+
+func TestLimiterGroup(t *testing.T) {
+	g := NewLimiterGroup(0, 0, 1)
+	if g.Get("Get") != g.Get("Get") {
+		t.Fatal("expected the same limiter for the same key")
+	}
+	if !g.Get("Get").TryAcquire() || !g.Get("Put").TryAcquire() {
+		t.Fatal("expected the limiters of different keys to be independent")
+	}
+}
@@ -0,0 +1,42 @@
+// Package runtime contains helpers shared by the code generated from the built-in templates,
+// so that it doesn't have to be duplicated into every generated file.
+// It's versioned together with wrappergen, generated code should use the runtime of the wrappergen version generating it.
+package runtime
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTimeout is returned by wrapped calls which didn't finish in time, when they can't be canceled using a context.
+var ErrTimeout = errors.New("call timed out")
+
+// Method describes a method of a wrapped type.
+type Method struct {
+	// The type name, with the package name prepended
+	// example: pkg.MyInterface
+	Interface string
+	// example: MyFunction
+	Name string
+}
+
+func (m Method) String() string {
+	return m.Interface + "." + m.Name
+}
+
+// CallInfo describes a single call of a wrapped method.
+type CallInfo struct {
+	Method
+	// The arguments of the call.
+	Args []interface{}
+	// Pointers to the results of the call, which are set once it returns.
+	Results []interface{}
+	// The time the call started at.
+	Start time.Time
+}
+
+// Interceptor is called for every call of a method of an intercepting wrapper, instead of the wrapped method.
+// It calls the wrapped method by calling next, which returns the error result of the method, if it has one.
+// The error returned by the interceptor is returned by the method, it's dropped for methods without an error result.
+type Interceptor func(ctx context.Context, call *CallInfo, next func() error) error
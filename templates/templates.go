@@ -0,0 +1,36 @@
+// Package templates contains the wrapper templates which are built into wrappergen.
+package templates
+
+import (
+	"embed"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed *.tmpl
+var files embed.FS
+
+// Get returns the contents of the built-in template with the given name.
+// example: prometheus
+func Get(name string) ([]byte, bool) {
+	data, err := files.ReadFile(name + ".tmpl")
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Names returns the names of all built-in templates, sorted.
+func Names() []string {
+	entries, err := files.ReadDir(".")
+	if err != nil {
+		return nil
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+	}
+	sort.Strings(names)
+	return names
+}
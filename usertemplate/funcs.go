@@ -0,0 +1,218 @@
+package usertemplate
+
+import (
+	"fmt"
+	"go/types"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// FuncMap contains the functions available in all template sections.
+//
+// Case conversions of identifiers, splitting words on case changes, underscores and dashes:
+// snakeCase: {{snakeCase "GetHTTPResponse"}} gives get_http_response
+// kebabCase: {{kebabCase "GetHTTPResponse"}} gives get-http-response
+// camelCase: {{camelCase "get_http_response"}} gives getHttpResponse
+// pascalCase: {{pascalCase "get_http_response"}} gives GetHttpResponse
+// lower, upper: {{lower .FunctionName}}
+//
+// Strings:
+// quote returns a Go string literal: {{quote .FunctionName}} gives "MyFunction"
+// join joins a list: {{join ", " .ReturnVars}} gives var0, err
+// hasPrefix: {{if hasPrefix .FunctionName "List"}}
+// indent indents every line with the given number of tabs: {{indent 1 .ZeroValuesReturn}}
+// duration returns an expression for the duration, failing the generation if it's invalid:
+// {{duration (.Annotations.Get "timeout")}} gives time.Duration(1500000000) for 1.5s
+//
+// Types, taking an element of MethodData.TypedArguments or MethodData.TypedReturnVars, or a type as a string:
+// typeOf returns the type: {{typeOf (index .TypedArguments 0)}} gives context.Context
+// isContext: {{if isContext (index .TypedArguments 0)}}
+// isError: {{if isError (index .TypedReturnVars 1)}}
+// zeroValue returns an expression for the zero value of the type: {{zeroValue "*pkg.Type"}} gives nil
+var FuncMap = template.FuncMap{
+	"snakeCase":  snakeCase,
+	"kebabCase":  kebabCase,
+	"camelCase":  camelCase,
+	"pascalCase": pascalCase,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"quote":      strconv.Quote,
+	"join":       join,
+	"hasPrefix":  strings.HasPrefix,
+	"indent":     indent,
+	"duration":   duration,
+	"typeOf":     typeOf,
+	"isContext":  isContext,
+	"isError":    isError,
+	"zeroValue":  zeroValue,
+}
+
+// Typed is implemented by the template data describing variables, like MethodData.TypedArguments.
+type Typed interface {
+	// The type as written in the generated code.
+	// example: context.Context
+	TypeString() string
+	GoType() types.Type
+}
+
+func duration(s string) (string, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return "", errors.Wrapf(err, "Invalid duration %v", s)
+	}
+	return fmt.Sprintf("time.Duration(%d)", int64(d)), nil
+}
+
+func snakeCase(s string) string {
+	return strings.Join(lowercaseWords(splitWords(s)), "_")
+}
+
+func kebabCase(s string) string {
+	return strings.Join(lowercaseWords(splitWords(s)), "-")
+}
+
+func camelCase(s string) string {
+	words := lowercaseWords(splitWords(s))
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.ToUpper(words[i][0:1]) + words[i][1:]
+	}
+	return strings.Join(words, "")
+}
+
+func pascalCase(s string) string {
+	words := lowercaseWords(splitWords(s))
+	for i := range words {
+		words[i] = strings.ToUpper(words[i][0:1]) + words[i][1:]
+	}
+	return strings.Join(words, "")
+}
+
+// Splits an identifier into words. An uppercase letter starts a new word, unless it's part of an acronym.
+// example: GetHTTPResponse gives []string{Get, HTTP, Response}
+func splitWords(s string) []string {
+	words := []string{}
+	runes := []rune(s)
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '_' || runes[i] == '-' || unicode.IsSpace(runes[i]) {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(runes[i]) {
+			continue
+		}
+		previousLower := !unicode.IsUpper(runes[i-1])
+		nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if previousLower || nextLower {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+func lowercaseWords(words []string) []string {
+	lowercase := make([]string, len(words))
+	for i := range words {
+		lowercase[i] = strings.ToLower(words[i])
+	}
+	return lowercase
+}
+
+func join(separator string, elements []string) string {
+	return strings.Join(elements, separator)
+}
+
+func indent(tabs int, s string) string {
+	prefix := strings.Repeat("\t", tabs)
+	lines := strings.Split(s, "\n")
+	for i := range lines {
+		if lines[i] != "" {
+			lines[i] = prefix + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func typeOf(v interface{}) string {
+	switch v := v.(type) {
+	case Typed:
+		return v.TypeString()
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func isContext(v interface{}) bool {
+	if typed, ok := v.(Typed); ok {
+		return types.TypeString(typed.GoType(), nil) == "context.Context"
+	}
+	return typeOf(v) == "context.Context"
+}
+
+func isError(v interface{}) bool {
+	if typed, ok := v.(Typed); ok {
+		return types.Identical(typed.GoType(), types.Universe.Lookup("error").Type())
+	}
+	return typeOf(v) == "error"
+}
+
+func zeroValue(v interface{}) string {
+	typed, ok := v.(Typed)
+	if !ok {
+		return zeroValueOfTypeString(typeOf(v))
+	}
+
+	switch underlying := typed.GoType().Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case underlying.Info()&types.IsBoolean != 0:
+			return "false"
+		case underlying.Info()&types.IsString != 0:
+			return `""`
+		case underlying.Info()&types.IsNumeric != 0:
+			return "0"
+		}
+		return "nil"
+	case *types.Pointer, *types.Slice, *types.Map, *types.Chan, *types.Signature, *types.Interface:
+		return "nil"
+	default:
+		return fmt.Sprintf("%s{}", typed.TypeString())
+	}
+}
+
+// Without type information, only predeclared types and type literals can be recognized.
+func zeroValueOfTypeString(typeString string) string {
+	switch typeString {
+	case "bool":
+		return "false"
+	case "string":
+		return `""`
+	case "error", "any":
+		return "nil"
+	}
+	if basic, ok := types.Universe.Lookup(typeString).(*types.TypeName); ok {
+		if basic, ok := basic.Type().(*types.Basic); ok && basic.Info()&types.IsNumeric != 0 {
+			return "0"
+		}
+	}
+	for _, prefix := range []string{"*", "[]", "map[", "chan ", "<-chan ", "func(", "interface{"} {
+		if strings.HasPrefix(typeString, prefix) {
+			return "nil"
+		}
+	}
+	return fmt.Sprintf("*new(%s)", typeString)
+}
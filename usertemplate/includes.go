@@ -0,0 +1,146 @@
+package usertemplate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/cube2222/StatsGenerator/templates"
+	"github.com/pkg/errors"
+)
+
+// Templates may share code in three ways, with paths relative to the template file they're given in:
+//
+// Include: lists files whose {{define}} actions are available to all sections, as partials used with {{template "name" .}}.
+//
+// Extends: names a base template. All sections of the base template are inherited,
+// the ones given in the extending template replace them, apart from Imports, Fields, Arguments, State, Params and Options,
+// which are added to the inherited ones.
+//
+// Blocks: contains {{define}} actions which override {{block}} actions of the base template, or define more partials.
+
+// Sections which are added to the inherited ones, instead of replacing them.
+var accumulatedSections = map[string]bool{
+	"Include":   true,
+	"Blocks":    true,
+	"Imports":   true,
+	"Fields":    true,
+	"Arguments": true,
+	"State":     true,
+	"Params":    true,
+	"Options":   true,
+}
+
+// Reads the template, following Extends. Include paths are resolved relative to the file they're given in.
+// The sections of an extending template come before the inherited ones.
+func loadSections(dir, name string, loading map[string]bool) ([]section, error) {
+	path := resolveTemplatePath(dir, name)
+	if loading[path] {
+		return nil, errors.Errorf("Template %v extends itself", path)
+	}
+	loading[path] = true
+	defer delete(loading, path)
+
+	data, err := readTemplate(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sections := []section{}
+	base := ""
+	for _, section := range getSections(data) {
+		switch section.name {
+		case "Extends":
+			base = string(section.value)
+			continue
+		case "Include":
+			section.value = []byte(strings.Join(resolveIncludes(filepath.Dir(path), section.value), "\n"))
+		}
+		sections = append(sections, section)
+	}
+	if base == "" {
+		return sections, nil
+	}
+
+	baseSections, err := loadSections(filepath.Dir(path), base, loading)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Couldn't load base template of %v", path)
+	}
+	for _, baseSection := range baseSections {
+		if accumulatedSections[baseSection.name] || getSection(sections, baseSection.name) == nil {
+			sections = append(sections, baseSection)
+		}
+	}
+
+	return sections, nil
+}
+
+// Parses the definitions of all Include and Blocks sections into tmpl.
+// The inherited sections are parsed first, so that the definitions of extending templates replace theirs.
+func parseDefinitions(tmpl *template.Template, sections []section) error {
+	for i := len(sections) - 1; i >= 0; i-- {
+		switch sections[i].name {
+		case "Include":
+			for _, path := range strings.Split(string(sections[i].value), "\n") {
+				if path == "" {
+					continue
+				}
+				data, err := readTemplate(path)
+				if err != nil {
+					return errors.Wrapf(err, "Couldn't read included template %v", path)
+				}
+				_, err = tmpl.New(path).Parse(string(data))
+				if err != nil {
+					return errors.Wrapf(err, "Couldn't parse included template %v", path)
+				}
+			}
+		case "Blocks":
+			_, err := tmpl.New("blocks").Parse(string(sections[i].value))
+			if err != nil {
+				return errors.Wrap(err, "Couldn't parse blocks template")
+			}
+		}
+	}
+	return nil
+}
+
+func resolveIncludes(dir string, section []byte) []string {
+	paths := []string{}
+	for _, line := range strings.Split(string(section), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			paths = append(paths, resolveTemplatePath(dir, line))
+		}
+	}
+	return paths
+}
+
+// Resolves the path relative to dir. If there's no such file, the name is kept as is, as it may be a built-in template.
+func resolveTemplatePath(dir, name string) string {
+	if filepath.IsAbs(name) || dir == "" {
+		return name
+	}
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err != nil {
+		if _, ok := templates.Get(name); ok {
+			return name
+		}
+	}
+	return path
+}
+
+// Reads the template file at path, falling back to the built-in template with that name if there's no such file.
+func readTemplate(path string) ([]byte, error) {
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		if builtin, ok := templates.Get(path); ok {
+			return builtin, nil
+		}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Couldn't read file")
+	}
+	return data, nil
+}
@@ -0,0 +1,87 @@
+package usertemplate
+
+import (
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// MethodOverride is a method template which replaces the default one for the methods its selector matches.
+// The selector is given in brackets in the section name, and is one of:
+// an exact method name, example: Method[Get]:
+// a regular expression between slashes, example: Method[/^List/]:
+// an annotation name after an @, example: Method[@cacheable]:
+type MethodOverride struct {
+	Selector string
+	Method   *template.Template
+
+	name       string
+	pattern    *regexp.Regexp
+	annotation string
+}
+
+// Matches reports whether the override applies to the method with the given name and annotations.
+func (o *MethodOverride) Matches(methodName string, annotations map[string][]string) bool {
+	switch {
+	case o.pattern != nil:
+		return o.pattern.MatchString(methodName)
+	case o.annotation != "":
+		_, ok := annotations[o.annotation]
+		return ok
+	default:
+		return o.name == methodName
+	}
+}
+
+// GetMethodTemplate returns the template of the first override matching the method, or the default Method template.
+func (t *TemplateData) GetMethodTemplate(methodName string, annotations map[string][]string) *template.Template {
+	for _, override := range t.MethodOverrides {
+		if override.Matches(methodName, annotations) {
+			return override.Method
+		}
+	}
+	return t.Method
+}
+
+func getMethodOverrides(tmpl *template.Template, sections []section) ([]*MethodOverride, error) {
+	overrides := []*MethodOverride{}
+	for _, section := range sections {
+		if !strings.HasPrefix(section.name, "Method[") {
+			continue
+		}
+		override, err := newMethodOverride(strings.TrimSuffix(strings.TrimPrefix(section.name, "Method["), "]"))
+		if err != nil {
+			return nil, err
+		}
+		override.Method, err = tmpl.New(section.name).Parse(string(section.value))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Couldn't parse %s template", section.name)
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides, nil
+}
+
+func newMethodOverride(selector string) (*MethodOverride, error) {
+	override := &MethodOverride{
+		Selector: selector,
+	}
+	switch {
+	case len(selector) > 1 && strings.HasPrefix(selector, "/") && strings.HasSuffix(selector, "/"):
+		pattern, err := regexp.Compile(selector[1 : len(selector)-1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Couldn't compile method selector %v", selector)
+		}
+		override.pattern = pattern
+	case strings.HasPrefix(selector, "@"):
+		override.annotation = strings.TrimPrefix(selector, "@")
+	default:
+		override.name = selector
+	}
+	if override.name == "" && override.pattern == nil && override.annotation == "" {
+		return nil, errors.Errorf("Empty method selector")
+	}
+	return override, nil
+}
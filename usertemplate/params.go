@@ -0,0 +1,177 @@
+package usertemplate
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Param is a parameter declared in the Params section of a template, one per line, as a name, a type and an optional default.
+// Parameters without a default are required.
+// The types are string, int, bool, float64 and duration, which is a time.Duration.
+// example:
+// namespace string = "app"
+// buckets int = 10
+// timeout duration = 5s
+// team string
+type Param struct {
+	Name     string
+	Type     string
+	Default  interface{}
+	Required bool
+}
+
+var paramTypes = map[string]bool{
+	"string":   true,
+	"int":      true,
+	"bool":     true,
+	"float64":  true,
+	"duration": true,
+}
+
+func getParams(section []byte) ([]*Param, error) {
+	params := []*Param{}
+	for _, line := range strings.Split(string(section), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		declaration := line
+		defaultValue := ""
+		hasDefault := false
+		if index := strings.Index(line, "="); index != -1 {
+			declaration = strings.TrimSpace(line[:index])
+			defaultValue = strings.TrimSpace(line[index+1:])
+			hasDefault = true
+		}
+
+		parts := strings.Fields(declaration)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("Invalid parameter declaration %v, expected: name type = default", line)
+		}
+		param := &Param{
+			Name:     parts[0],
+			Type:     parts[1],
+			Required: !hasDefault,
+		}
+		if !paramTypes[param.Type] {
+			return nil, errors.Errorf("Unknown type %v of parameter %v", param.Type, param.Name)
+		}
+		if hasDefault {
+			value, err := parseParamValue(param.Type, defaultValue)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Invalid default of parameter %v", param.Name)
+			}
+			param.Default = value
+		}
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+// Resolves the values of the parameters, using the defaults for the ones which aren't given.
+func getParamValues(params []*Param, values map[string]string, ignoreUnknown bool) (map[string]interface{}, error) {
+	declared := map[string]*Param{}
+	for _, param := range params {
+		if _, ok := declared[param.Name]; !ok {
+			declared[param.Name] = param
+		}
+	}
+
+	names := []string{}
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resolved := map[string]interface{}{}
+	for _, name := range names {
+		param, ok := declared[name]
+		if !ok && ignoreUnknown {
+			continue
+		}
+		if !ok {
+			return nil, errors.Errorf("Unknown template parameter %v", name)
+		}
+		value, err := parseParamValue(param.Type, values[name])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid value of template parameter %v", name)
+		}
+		resolved[name] = value
+	}
+
+	for _, param := range params {
+		if _, ok := resolved[param.Name]; ok {
+			continue
+		}
+		if param.Required {
+			return nil, errors.Errorf("Missing required template parameter %v", param.Name)
+		}
+		resolved[param.Name] = param.Default
+	}
+
+	return resolved, nil
+}
+
+func parseParamValue(paramType, value string) (interface{}, error) {
+	switch paramType {
+	case "string":
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted, nil
+		}
+		return value, nil
+	case "int":
+		return strconv.Atoi(value)
+	case "bool":
+		return strconv.ParseBool(value)
+	case "float64":
+		return strconv.ParseFloat(value, 64)
+	case "duration":
+		return time.ParseDuration(value)
+	default:
+		return nil, errors.Errorf("Unknown parameter type %v", paramType)
+	}
+}
+
+// ReadParamsManifest reads the values of template parameters from the manifest file at path,
+// one per line as name=value, like the values given on the command line.
+// Empty lines and lines starting with # are ignored.
+// example:
+// namespace=billing
+// timeout=10s
+func ReadParamsManifest(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Couldn't open parameters manifest")
+	}
+	defer file.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		index := strings.Index(line, "=")
+		if index == -1 {
+			return nil, errors.Errorf("Invalid line %v of parameters manifest %v, expected: name=value", lineNumber, path)
+		}
+		name := strings.TrimSpace(line[:index])
+		if _, ok := values[name]; ok {
+			return nil, errors.Errorf("Template parameter %v given multiple times in parameters manifest %v", name, path)
+		}
+		values[name] = strings.TrimSpace(line[index+1:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Couldn't read parameters manifest")
+	}
+
+	return values, nil
+}
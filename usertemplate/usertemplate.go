@@ -1,7 +1,6 @@
 package usertemplate
 
 import (
-	"io/ioutil"
 	"strings"
 	"text/template"
 
@@ -11,11 +10,34 @@ import (
 )
 
 type TemplateData struct {
-	Fields  []UserSuppliedField
+	// Fields are stored in the wrapper and taken as constructor arguments.
+	Fields []UserSuppliedField
+	// Arguments are taken as constructor arguments, but not stored in the wrapper.
+	Arguments []UserSuppliedField
+	// State fields are stored in the wrapper, but have to be initialized by the Constructor section.
+	State   []UserSuppliedField
 	Imports []string
-	Method  *template.Template
-	Package string
-	Suffix  string
+	// Constructor is executed inside of the generated constructor, before the wrapper is returned. May be nil.
+	Constructor *template.Template
+	// Declarations is executed after the constructor, it may contain additional top level declarations. May be nil.
+	Declarations *template.Template
+	Method       *template.Template
+	// MethodOverrides replace the Method template for the methods they match, the first matching one is used.
+	MethodOverrides []*MethodOverride
+	Package         string
+	Suffix          string
+	// Params holds the values of the parameters declared in the Params section, by name, see Param.
+	Params map[string]interface{}
+	// ParamDeclarations are the parameters declared in the Params section.
+	ParamDeclarations []*Param
+	// NamedResults makes the generated methods use named results, so that deferred code can read and assign them.
+	NamedResults bool
+	// ReturnWrapper makes the constructor return a pointer to the wrapper, instead of the wrapped interface,
+	// so that the methods added by the Declarations section are accessible.
+	ReturnWrapper bool
+	// CacheKeys marks templates using the arguments of cacheable methods as cache keys,
+	// so that the annotated methods are validated.
+	CacheKeys bool
 }
 
 type UserSuppliedField struct {
@@ -27,66 +49,248 @@ func (f *UserSuppliedField) String() string {
 }
 
 type WrapperTemplateConfig struct {
+	// Path of the template file, or the name of a built-in template.
 	Path string
+	// Values of the template parameters, by name. Parameters which aren't given use their defaults.
+	Params map[string]string
+	// Ignore values of parameters which the template doesn't declare, instead of failing.
+	// Used when the same values are given to multiple templates.
+	IgnoreUnknownParams bool
+}
+
+// The sections a template file may consist of. A section starts with a line containing its name followed by a colon,
+// the rest of the line being the first line of its value.
+// example: Package: wrappers
+// Method sections may additionally contain a selector in brackets, see MethodOverride.
+var sectionNames = []string{
+	"Extends",
+	"Include",
+	"Blocks",
+	"Params",
+	"Options",
+	"Package",
+	"Suffix",
+	"Imports",
+	"Fields",
+	"Arguments",
+	"State",
+	"Constructor",
+	"Declarations",
+	"Method",
+}
+
+// Sections containing code, in which a line like "State: state," may be part of the code.
+// Inside of them, only a line containing nothing but a section name followed by a colon starts a new section.
+var codeSectionNames = map[string]bool{
+	"Blocks":       true,
+	"Constructor":  true,
+	"Declarations": true,
+	"Method":       true,
 }
 
 func GetWrapperTemplate(config *WrapperTemplateConfig) (*TemplateData, error) {
-	data, err := ioutil.ReadFile(config.Path)
+	sections, err := loadSections("", config.Path, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	imports := []string{}
+	for _, line := range strings.Split(string(getJoinedSections(sections, "Imports")), "\n") {
+		if line != "" {
+			imports = append(imports, line)
+		}
+	}
+
+	tmpl, err := template.New("method").Funcs(FuncMap).Parse(string(getSection(sections, "Method")))
+	if err != nil {
+		return nil, errors.Wrap(err, "Couldn't parse template")
+	}
+
+	constructor, err := getOptionalTemplate(tmpl, "constructor", getSection(sections, "Constructor"))
+	if err != nil {
+		return nil, err
+	}
+	declarations, err := getOptionalTemplate(tmpl, "declarations", getSection(sections, "Declarations"))
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := getMethodOverrides(tmpl, sections)
+	if err != nil {
+		return nil, err
+	}
+	err = parseDefinitions(tmpl, sections)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := getParams(getJoinedSections(sections, "Params"))
+	if err != nil {
+		return nil, err
+	}
+	paramValues, err := getParamValues(params, config.Params, config.IgnoreUnknownParams)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := getOptions(getJoinedSections(sections, "Options"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &TemplateData{
+		Imports:           imports,
+		Fields:            getFields(getJoinedSections(sections, "Fields")),
+		Arguments:         getFields(getJoinedSections(sections, "Arguments")),
+		State:             getFields(getJoinedSections(sections, "State")),
+		Constructor:       constructor,
+		Declarations:      declarations,
+		Method:            tmpl,
+		MethodOverrides:   overrides,
+		Package:           string(getSection(sections, "Package")),
+		Suffix:            string(getSection(sections, "Suffix")),
+		Params:            paramValues,
+		ParamDeclarations: params,
+		NamedResults:      options[namedResultsOption],
+		ReturnWrapper:     options[returnWrapperOption],
+		CacheKeys:         options[cacheKeysOption],
+	}, nil
+}
+
+// Parses the section as a template associated with tmpl, so that they share definitions. Returns nil for an empty section.
+func getOptionalTemplate(tmpl *template.Template, name string, section []byte) (*template.Template, error) {
+	if len(section) == 0 {
+		return nil, nil
+	}
+	optional, err := tmpl.New(name).Parse(string(section))
 	if err != nil {
-		return nil, errors.Wrap(err, "Couldn't read file")
+		return nil, errors.Wrapf(err, "Couldn't parse %s template", name)
 	}
+	return optional, nil
+}
 
-	packagePart := getField(data, []byte("Package"), []byte("Suffix"))
+// The options which may be given in the Options section, one per line.
+const (
+	// Generate methods with named results.
+	namedResultsOption = "namedResults"
+	// Return a pointer to the wrapper from the constructor.
+	returnWrapperOption = "returnWrapper"
+	// Use the arguments of methods annotated as cacheable as cache keys, which requires them to be strictly comparable.
+	cacheKeysOption = "cacheKeys"
+)
 
-	suffixPart := getField(data, []byte("Suffix"), []byte("Imports"))
+var knownOptions = map[string]bool{
+	namedResultsOption:  true,
+	returnWrapperOption: true,
+	cacheKeysOption:     true,
+}
 
-	importsPart := getField(data, []byte("Imports"), []byte("Fields"))
-	imports := strings.Split(string(importsPart), "\n")
+func getOptions(section []byte) (map[string]bool, error) {
+	options := map[string]bool{}
+	for _, line := range strings.Split(string(section), "\n") {
+		option := strings.TrimSpace(line)
+		if option == "" {
+			continue
+		}
+		if !knownOptions[option] {
+			return nil, errors.Errorf("Unknown template option %v", option)
+		}
+		options[option] = true
+	}
+	return options, nil
+}
 
-	fieldsPart := getField(data, []byte("Fields"), []byte("Method"))
-	fieldsStrings := bytes.Split(fieldsPart, []byte("\n"))
+func getFields(section []byte) []UserSuppliedField {
+	fieldsStrings := bytes.Split(section, []byte("\n"))
 	fields := []UserSuppliedField{}
 	for _, fieldString := range fieldsStrings {
-		parts := bytes.Split(fieldString, []byte(" "))
+		parts := bytes.SplitN(bytes.TrimSpace(fieldString), []byte(" "), 2)
 		if len(parts) < 2 {
-			break
+			continue
 		}
 		fields = append(fields, UserSuppliedField{
 			Varname:  string(parts[0]),
-			Typename: string(parts[1]),
+			Typename: string(bytes.TrimSpace(parts[1])),
 		})
 	}
+	return fields
+}
 
-	methodPart := getField(data, []byte("Method"), nil)
-	tmpl, err := template.New("method").Parse(string(methodPart))
-	if err != nil {
-		return nil, errors.Wrap(err, "Couldn't parse template")
+type section struct {
+	// The section name, including the selector if present.
+	// example: Method[Get]
+	name  string
+	value []byte
+}
+
+// Splits the template file into its sections, in the order they appear in.
+func getSections(data []byte) []section {
+	data = bytes.Replace(data, []byte("\r"), []byte{}, -1)
+
+	sections := []section{}
+	var value [][]byte
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		inCode := len(sections) > 0 && isCodeSection(sections[len(sections)-1].name)
+		if name, inlineValue, ok := getSectionName(line, inCode); ok {
+			if len(sections) > 0 {
+				sections[len(sections)-1].value = bytes.Trim(bytes.Join(value, []byte("\n")), "\n ")
+			}
+			sections = append(sections, section{name: name})
+			value = [][]byte{inlineValue}
+			continue
+		}
+		value = append(value, line)
+	}
+	if len(sections) > 0 {
+		sections[len(sections)-1].value = bytes.Trim(bytes.Join(value, []byte("\n")), "\n ")
 	}
 
-	return &TemplateData{
-		Imports: imports,
-		Fields:  fields,
-		Method:  tmpl,
-		Package: string(packagePart),
-		Suffix:  string(suffixPart),
-	}, nil
+	return sections
 }
 
-func getField(data []byte, field []byte, nextField []byte) []byte {
-	key := bytes.Join([][]byte{field, []byte(":")}, nil)
-	valueIndex := bytes.Index(data, key)
+// Returns the value of the first section with the given name, nil if there is none.
+func getSection(sections []section, name string) []byte {
+	for _, section := range sections {
+		if section.name == name {
+			return section.value
+		}
+	}
+	return nil
+}
 
-	var value []byte
-	if nextField != nil {
-		endKey := bytes.Join([][]byte{nextField, []byte(":")}, nil)
-		valueEndIndex := bytes.Index(data, endKey)
-		value = bytes.TrimPrefix(data[valueIndex:valueEndIndex], key)
-	} else {
-		value = bytes.TrimPrefix(data[valueIndex:], key)
+// Returns the values of all sections with the given name, one after another.
+func getJoinedSections(sections []section, name string) []byte {
+	values := [][]byte{}
+	for _, section := range sections {
+		if section.name == name {
+			values = append(values, section.value)
+		}
 	}
+	return bytes.Join(values, []byte("\n"))
+}
 
-	value = bytes.Replace(value, []byte("\r"), []byte{}, -1)
-	value = bytes.Trim(value, "\r\n ")
+// Returns the name of the section the line starts, and the value given on the same line, if any.
+// Inside of code sections, values on the same line aren't allowed, so that code isn't mistaken for a section.
+func getSectionName(line []byte, inCode bool) (string, []byte, bool) {
+	line = bytes.TrimSpace(line)
+	colon := bytes.IndexByte(line, ':')
+	if colon == -1 {
+		return "", nil, false
+	}
+	name, value := string(line[:colon]), bytes.TrimSpace(line[colon+1:])
+	if inCode && len(value) > 0 {
+		return "", nil, false
+	}
+	for _, sectionName := range sectionNames {
+		if name == sectionName {
+			return name, value, true
+		}
+	}
+	if strings.HasPrefix(name, "Method[") && strings.HasSuffix(name, "]") {
+		return name, value, true
+	}
+	return "", nil, false
+}
 
-	return value
+func isCodeSection(name string) bool {
+	return codeSectionNames[name] || strings.HasPrefix(name, "Method[")
 }